@@ -0,0 +1,97 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// Default returns the configuration of an operator started without
+// configuration file.
+func Default() OperatorConfig {
+	config := OperatorConfig{}
+	config.APIVersion = APIVersion
+	config.Kind = Kind
+	config.SetDefaults()
+	return config
+}
+
+// SetDefaults fills in the unset fields.
+func (c *OperatorConfig) SetDefaults() {
+	if c.ProjectLabel == "" {
+		c.ProjectLabel = "project"
+	}
+	if c.ProjectQuotaName == "" {
+		c.ProjectQuotaName = "project-quota"
+	}
+	if c.WebhookPort == 0 {
+		c.WebhookPort = 9443
+	}
+	if c.LeaderElectionID == "" {
+		c.LeaderElectionID = "f4bd82be.my.domain"
+	}
+	if c.Accounting.Mode == "" {
+		c.Accounting.Mode = DefaultAccountingMode
+	}
+}
+
+// Load reads an OperatorConfig from a YAML or JSON file, such as a mounted
+// ConfigMap, and defaults it. Unknown fields are refused, so that a typo does
+// not silently keep a default.
+func Load(path string) (OperatorConfig, error) {
+	config := OperatorConfig{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return config, fmt.Errorf("invalid operator configuration %s: %v", path, err)
+	}
+	if config.APIVersion != APIVersion || config.Kind != Kind {
+		return config, fmt.Errorf("invalid operator configuration %s: expected apiVersion %s and kind %s, got %q and %q",
+			path, APIVersion, Kind, config.APIVersion, config.Kind)
+	}
+	config.SetDefaults()
+	return config, nil
+}
+
+// Validate returns an error listing every invalid field. The accounting mode
+// is left to the budget package, which parses it.
+func (c *OperatorConfig) Validate() error {
+	var problems []string
+	for _, message := range validation.IsQualifiedName(c.ProjectLabel) {
+		problems = append(problems, "projectLabel: "+message)
+	}
+	for _, message := range validation.IsDNS1123Subdomain(c.ProjectQuotaName) {
+		problems = append(problems, "projectQuotaName: "+message)
+	}
+	for _, message := range validation.IsValidPortNum(c.WebhookPort) {
+		problems = append(problems, "webhookPort: "+message)
+	}
+	for _, message := range validation.IsDNS1123Subdomain(c.LeaderElectionID) {
+		problems = append(problems, "leaderElectionID: "+message)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid operator configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
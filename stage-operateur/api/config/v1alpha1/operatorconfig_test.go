@@ -0,0 +1,103 @@
+package v1alpha1
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func writeConfig(content string) string {
+	dir, err := ioutil.TempDir("", "operator-config")
+	Expect(err).NotTo(HaveOccurred())
+	path := filepath.Join(dir, "operator_config.yaml")
+	Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+	return path
+}
+
+var _ = Describe("Load", func() {
+	It("should read the configuration and default the unset fields", func() {
+		// Given
+		path := writeConfig(`apiVersion: config.project.my.domain/v1alpha1
+kind: OperatorConfig
+projectLabel: team
+accounting:
+  mode: Exclusive
+namespaceLabel:
+  groups: [platform]
+`)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		// When
+		config, err := Load(path)
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.ProjectLabel).To(Equal("team"))
+		Expect(config.ProjectQuotaName).To(Equal("project-quota"))
+		Expect(config.WebhookPort).To(Equal(9443))
+		Expect(config.LeaderElectionID).To(Equal("f4bd82be.my.domain"))
+		Expect(config.Accounting.Mode).To(Equal("Exclusive"))
+		Expect(config.NamespaceLabel.Groups).To(Equal([]string{"platform"}))
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should refuse unknown fields", func() {
+		// Given
+		path := writeConfig(`apiVersion: config.project.my.domain/v1alpha1
+kind: OperatorConfig
+projectLable: team
+`)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		// When
+		_, err := Load(path)
+
+		// Then
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should refuse another kind or version", func() {
+		// Given
+		path := writeConfig(`apiVersion: config.project.my.domain/v1
+kind: OperatorConfig
+`)
+		defer os.RemoveAll(filepath.Dir(path))
+
+		// When
+		_, err := Load(path)
+
+		// Then
+		Expect(err).To(MatchError(ContainSubstring("expected apiVersion config.project.my.domain/v1alpha1 and kind OperatorConfig")))
+	})
+})
+
+var _ = Describe("Validate", func() {
+	It("should accept the default configuration", func() {
+		// Given
+		config := Default()
+
+		// Then
+		Expect(config.Validate()).To(Succeed())
+	})
+
+	It("should list every invalid field", func() {
+		// Given
+		config := Default()
+		config.ProjectLabel = "not a label"
+		config.ProjectQuotaName = "Project_Quota"
+		config.WebhookPort = 70000
+
+		// When
+		err := config.Validate()
+
+		// Then
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("projectLabel: "))
+		Expect(err.Error()).To(ContainSubstring("projectQuotaName: "))
+		Expect(err.Error()).To(ContainSubstring("webhookPort: "))
+		Expect(err.Error()).NotTo(ContainSubstring("leaderElectionID"))
+	})
+})
@@ -0,0 +1,77 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 contains the configuration file of the operator, loaded
+// at startup.
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// APIVersion of the OperatorConfig of this package
+	APIVersion = "config.project.my.domain/v1alpha1"
+	// Kind of the OperatorConfig
+	Kind = "OperatorConfig"
+	// DefaultAccountingMode is the accounting mode of an OperatorConfig
+	// that sets none
+	DefaultAccountingMode = "ManagedQuota"
+)
+
+// OperatorConfig configures the operator. Every field is optional; an unset
+// field keeps its default.
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ProjectLabel is the label of a namespace naming its project.
+	// Defaults to project
+	ProjectLabel string `json:"projectLabel,omitempty"`
+
+	// ProjectQuotaName is the name of the ResourceQuota the operator
+	// maintains in every project namespace. Defaults to project-quota
+	ProjectQuotaName string `json:"projectQuotaName,omitempty"`
+
+	// WebhookPort is the port the webhook server listens on. Defaults to 9443
+	WebhookPort int `json:"webhookPort,omitempty"`
+
+	// LeaderElectionID is the name of the ConfigMap used for leader
+	// election. Defaults to f4bd82be.my.domain
+	LeaderElectionID string `json:"leaderElectionID,omitempty"`
+
+	// Accounting sets how ResourceQuotas count against the project limits
+	Accounting AccountingConfig `json:"accounting,omitempty"`
+
+	// NamespaceLabel restricts who may set or change the project label of a namespace
+	NamespaceLabel NamespaceLabelConfig `json:"namespaceLabel,omitempty"`
+}
+
+// AccountingConfig sets how ResourceQuotas count against the project limits
+type AccountingConfig struct {
+	// Mode is ManagedQuota, AllQuotas or Exclusive. Defaults to ManagedQuota
+	Mode string `json:"mode,omitempty"`
+
+	// AllowLimitShrink lets project limits be lowered below what the
+	// project-quotas already allocate; the project is then OverCommitted
+	AllowLimitShrink bool `json:"allowLimitShrink,omitempty"`
+}
+
+// NamespaceLabelConfig lists who may add, change or remove the project label
+// of a namespace. When both are empty, anyone who can update the namespace may
+type NamespaceLabelConfig struct {
+	Users  []string `json:"users,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
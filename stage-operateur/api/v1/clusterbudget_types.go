@@ -0,0 +1,118 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterBudgetSpec defines the desired state of ClusterBudget
+type ClusterBudgetSpec struct {
+	// Capacity is, for every resource named as a project limit, what the
+	// projects of the cluster may commit in total
+	//	+optional
+	Capacity corev1.ResourceList `json:"capacity,omitempty"`
+
+	// NodeAllocatable completes the capacity with the allocatable resources
+	// of the schedulable nodes, for the resources Capacity does not name
+	//	+optional
+	NodeAllocatable bool `json:"nodeAllocatable,omitempty"`
+
+	// Enforcement tells whether the project webhook denies project limits
+	// that do not fit in the capacity, or only reports them in the status.
+	// Defaults to Deny
+	//	+optional
+	Enforcement ClusterBudgetEnforcement `json:"enforcement,omitempty"`
+}
+
+// ClusterBudgetEnforcement is what happens to project limits that do not fit in a ClusterBudget
+// +kubebuilder:validation:Enum=Deny;Report
+type ClusterBudgetEnforcement string
+
+const (
+	// ClusterBudgetDeny denies the projects whose limits do not fit in the capacity
+	ClusterBudgetDeny ClusterBudgetEnforcement = "Deny"
+	// ClusterBudgetReport only reports the cluster as OverCommitted
+	ClusterBudgetReport ClusterBudgetEnforcement = "Report"
+)
+
+// ClusterBudgetStatus defines the observed state of ClusterBudget
+type ClusterBudgetStatus struct {
+	// Capacity is the capacity the projects are checked against, Capacity of
+	// the spec completed by the allocatable resources of the nodes
+	//	+optional
+	Capacity corev1.ResourceList `json:"capacity,omitempty"`
+
+	// Committed is, for every resource of the capacity, the sum of the
+	// projectLimits of the projects without parent. Children are carved from
+	// their parent and are not counted again
+	//	+optional
+	Committed corev1.ResourceList `json:"committed,omitempty"`
+
+	// Remaining is the capacity minus what is committed. It is negative when
+	// the cluster is over-committed
+	//	+optional
+	Remaining corev1.ResourceList `json:"remaining,omitempty"`
+
+	// Projects is the number of projects without parent
+	//	+optional
+	Projects int `json:"projects,omitempty"`
+
+	// Conditions of the cluster budget: OverCommitted
+	//	+optional
+	Conditions []ProjectCondition `json:"conditions,omitempty"`
+}
+
+// ClusterBudgetOverCommitted is true when the projects commit more than the capacity
+const ClusterBudgetOverCommitted = "OverCommitted"
+
+// EnforcementOrDefault is the enforcement of the budget, Deny when unset.
+func (b *ClusterBudget) EnforcementOrDefault() ClusterBudgetEnforcement {
+	if b.Spec.Enforcement == "" {
+		return ClusterBudgetDeny
+	}
+	return b.Spec.Enforcement
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=clusterbudgets,scope=Cluster
+// +kubebuilder:printcolumn:name="Projects",type=integer,JSONPath=`.status.projects`
+// +kubebuilder:printcolumn:name="OverCommitted",type=string,JSONPath=`.status.conditions[?(@.type=="OverCommitted")].status`
+// ClusterBudget is the capacity of the cluster that the limits of all the
+// projects are checked against
+type ClusterBudget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterBudgetSpec   `json:"spec,omitempty"`
+	Status ClusterBudgetStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterBudgetList contains a list of ClusterBudget
+type ClusterBudgetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterBudget `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterBudget{}, &ClusterBudgetList{})
+}
@@ -0,0 +1,51 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// ChildProjects returns the projects whose parent is the named project
+func ChildProjects(projects []Project, name string) []Project {
+	var children []Project
+	for _, project := range projects {
+		if project.Spec.Parent == name && project.Name != name {
+			children = append(children, project)
+		}
+	}
+	return children
+}
+
+// DescendantProjects returns the names of the children of the named project,
+// of their children, and so on
+func DescendantProjects(projects []Project, name string) []string {
+	visited := map[string]bool{name: true}
+	var descendants []string
+	for queue := []string{name}; len(queue) > 0; queue = queue[1:] {
+		for _, child := range ChildProjects(projects, queue[0]) {
+			if visited[child.Name] {
+				continue
+			}
+			visited[child.Name] = true
+			descendants = append(descendants, child.Name)
+			queue = append(queue, child.Name)
+		}
+	}
+	return descendants
+}
+
+// RootProjects returns the projects without parent
+func RootProjects(projects []Project) []Project {
+	return ChildProjects(projects, "")
+}
@@ -0,0 +1,120 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// NamespaceRequestSpec defines the desired state of NamespaceRequest
+type NamespaceRequestSpec struct {
+	// Namespace is the name of the namespace to create
+	Namespace string `json:"namespace"`
+
+	// Quota is the spec.hard of the project-quota of the namespace
+	//	+optional
+	Quota corev1.ResourceList `json:"quota,omitempty"`
+}
+
+// NamespaceRequestPhase is where a NamespaceRequest stands
+type NamespaceRequestPhase string
+
+const (
+	// NamespaceRequestPending is a request not handled yet
+	NamespaceRequestPending NamespaceRequestPhase = "Pending"
+	// NamespaceRequestCreated is a request whose namespace has been created
+	NamespaceRequestCreated NamespaceRequestPhase = "Created"
+	// NamespaceRequestDenied is a request that cannot be granted, the message
+	// tells why
+	NamespaceRequestDenied NamespaceRequestPhase = "Denied"
+)
+
+// NamespaceRequestStatus defines the observed state of NamespaceRequest
+type NamespaceRequestStatus struct {
+	// Phase of the request: Pending, Created or Denied
+	//	+optional
+	Phase NamespaceRequestPhase `json:"phase,omitempty"`
+
+	// Project the namespace is created in, the one of the namespace of the request
+	//	+optional
+	Project string `json:"project,omitempty"`
+
+	// Message is a human readable description of the phase
+	//	+optional
+	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration is the generation of the request the phase is for. A
+	// Denied request is only handled again once its spec changes
+	//	+optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// NamespaceRequestAnnotation is set on a namespace created for a
+// NamespaceRequest; its value is <namespace>/<name> of the request
+const NamespaceRequestAnnotation = "project.my.domain/namespace-request"
+
+// NamespaceRequestOf returns the NamespaceRequest the namespace was created
+// for, from its NamespaceRequestAnnotation.
+func NamespaceRequestOf(namespace metav1.Object) (types.NamespacedName, bool) {
+	parts := strings.SplitN(namespace.GetAnnotations()[NamespaceRequestAnnotation], "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, true
+}
+
+// QuotaSpec is the spec of the project-quota of the requested namespace: the
+// defaultNamespaceQuota of the project, with the requested quota as hard when
+// set.
+func (r *NamespaceRequest) QuotaSpec(project *Project) corev1.ResourceQuotaSpec {
+	spec := project.NamespaceQuotaSpec()
+	if r.Spec.Quota != nil {
+		spec.Hard = r.Spec.Quota.DeepCopy()
+	}
+	return spec
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.spec.namespace`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// NamespaceRequest asks for a new namespace in the project of the namespace it
+// is created in
+type NamespaceRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NamespaceRequestSpec   `json:"spec,omitempty"`
+	Status NamespaceRequestStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NamespaceRequestList contains a list of NamespaceRequest
+type NamespaceRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NamespaceRequest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NamespaceRequest{}, &NamespaceRequestList{})
+}
@@ -18,7 +18,12 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
@@ -31,6 +36,176 @@ type ProjectSpec struct {
 	//Limits
 	//	+optional
 	ProjectLimits corev1.ResourceList `json:"projectLimits,omitempty"`
+
+	// Parent is the name of the project whose budget this project's limits
+	// are carved from
+	//	+optional
+	Parent string `json:"parent,omitempty"`
+
+	// DeletionPolicy tells what happens to the namespaces of the project when
+	// it is deleted. Defaults to Orphan
+	//	+optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// NamespaceTemplate lists the objects created, and kept in sync, in every
+	// namespace of the project
+	//	+optional
+	NamespaceTemplate *NamespaceTemplate `json:"namespaceTemplate,omitempty"`
+
+	// Members are granted their role in every namespace of the project
+	//	+optional
+	Members []ProjectMember `json:"members,omitempty"`
+
+	// NetworkIsolation restricts the ingress traffic of the namespaces of the
+	// project. Defaults to none
+	//	+optional
+	NetworkIsolation NetworkIsolation `json:"networkIsolation,omitempty"`
+
+	// QuotaAutoApproval is, for every resource, the largest increase a
+	// QuotaChangeRequest gets without the approval of a project admin.
+	// Decreases are always approved
+	//	+optional
+	QuotaAutoApproval corev1.ResourceList `json:"quotaAutoApproval,omitempty"`
+
+	// DefaultNamespaceQuota is the spec of the project-quota created in every
+	// new namespace of the project. Defaults to no cpu and no memory
+	//	+optional
+	DefaultNamespaceQuota *corev1.ResourceQuotaSpec `json:"defaultNamespaceQuota,omitempty"`
+
+	// Overcommit is, for some limits.* resources, the factor by which the
+	// resourceQuotas of the project may allocate more than its limit, 1.5
+	// allowing 50% more. requests.* resources, and the short cpu, memory and
+	// ephemeral-storage names which request them, cannot be overcommitted
+	//	+optional
+	Overcommit corev1.ResourceList `json:"overcommit,omitempty"`
+}
+
+// NetworkIsolation is where the ingress traffic of a project namespace may come from
+// +kubebuilder:validation:Enum=none;project;namespace
+type NetworkIsolation string
+
+const (
+	// NetworkIsolationNone lets any traffic in
+	NetworkIsolationNone NetworkIsolation = "none"
+	// NetworkIsolationProject only lets in the traffic of the namespaces of
+	// the same project
+	NetworkIsolationProject NetworkIsolation = "project"
+	// NetworkIsolationNamespace only lets in the traffic of the namespace itself
+	NetworkIsolationNamespace NetworkIsolation = "namespace"
+)
+
+// NetworkIsolationLabel marks the NetworkPolicy isolating a project
+// namespace; its value is the name of the project
+const NetworkIsolationLabel = "project.my.domain/network-isolation"
+
+// ProjectMember is a user, group or service account with a role in the project
+type ProjectMember struct {
+	// Kind of the member: User, Group or ServiceAccount
+	// +kubebuilder:validation:Enum=User;Group;ServiceAccount
+	Kind string `json:"kind"`
+
+	// Name of the member
+	Name string `json:"name"`
+
+	// Namespace of a ServiceAccount member. Defaults to each namespace of the
+	// project
+	//	+optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Role of the member, bound through the ClusterRole of the same name
+	// +kubebuilder:validation:Enum=admin;edit;view
+	Role ProjectRole `json:"role"`
+}
+
+// ProjectRole is the role of a member in the namespaces of a project
+type ProjectRole string
+
+// Roles a member can have in a project
+const (
+	ProjectRoleAdmin ProjectRole = "admin"
+	ProjectRoleEdit  ProjectRole = "edit"
+	ProjectRoleView  ProjectRole = "view"
+)
+
+// ProjectMembersLabel marks the RoleBindings granting the project members
+// their role; its value is the name of the project
+const ProjectMembersLabel = "project.my.domain/members"
+
+// NamespaceTemplate is the set of objects stamped into every namespace of a project
+type NamespaceTemplate struct {
+	// Objects are LimitRanges, ConfigMaps, NetworkPolicies and RoleBindings
+	// to the admin, edit or view ClusterRole. Their namespace is set to the
+	// namespace they are stamped into
+	//	+optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Objects []runtime.RawExtension `json:"objects,omitempty"`
+}
+
+// NamespaceTemplateLabel marks the objects stamped from a project's namespace
+// template; its value is the name of the project
+const NamespaceTemplateLabel = "project.my.domain/template"
+
+// NamespaceTemplateKinds returns the kinds of objects a namespace template
+// may hold
+func NamespaceTemplateKinds() []schema.GroupVersionKind {
+	return []schema.GroupVersionKind{
+		corev1.SchemeGroupVersion.WithKind("LimitRange"),
+		corev1.SchemeGroupVersion.WithKind("ConfigMap"),
+		networkingv1.SchemeGroupVersion.WithKind("NetworkPolicy"),
+		rbacv1.SchemeGroupVersion.WithKind("RoleBinding"),
+	}
+}
+
+// DeletionPolicy is what happens to the namespaces of a deleted project
+// +kubebuilder:validation:Enum=Orphan;Delete;Retain
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyOrphan removes the project label and the project-quota of
+	// the namespaces, which are kept
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+	// DeletionPolicyDelete deletes the namespaces of the project
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain blocks the deletion of the project as long as it
+	// has namespaces
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
+// ProjectFinalizer holds a deleted Project until its namespaces have been
+// handled according to its deletion policy
+const ProjectFinalizer = "project.my.domain/finalizer"
+
+// ProjectLabel is the default label of a namespace naming its project, and
+// ProjectQuotaName the default name of the ResourceQuota the operator
+// maintains in every project namespace
+const (
+	ProjectLabel     = "project"
+	ProjectQuotaName = "project-quota"
+)
+
+// Names are the project label and the project-quota name the operator is
+// configured with. The zero value keeps the defaults.
+// +kubebuilder:object:generate=false
+type Names struct {
+	ProjectLabel     string
+	ProjectQuotaName string
+}
+
+// Label returns the label of a namespace naming its project
+func (n Names) Label() string {
+	if n.ProjectLabel == "" {
+		return ProjectLabel
+	}
+	return n.ProjectLabel
+}
+
+// QuotaName returns the name of the ResourceQuota the operator maintains in
+// every project namespace
+func (n Names) QuotaName() string {
+	if n.ProjectQuotaName == "" {
+		return ProjectQuotaName
+	}
+	return n.ProjectQuotaName
 }
 
 // ProjectStatus defines the observed state of Project
@@ -38,6 +213,149 @@ type ProjectSpec struct {
 type ProjectStatus struct {
 	//+optional
 	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Allocated is, for every project limit, the sum of the spec.hard of the
+	// project-quota of every namespace
+	//	+optional
+	Allocated corev1.ResourceList `json:"allocated,omitempty"`
+
+	// Used is, for every project limit, the sum of the status.used of the
+	// project-quota of every namespace
+	//	+optional
+	Used corev1.ResourceList `json:"used,omitempty"`
+
+	// Remaining is, for every project limit, what is left once the allocated
+	// quotas and the limits carved by the children are subtracted from the
+	// limit times its overcommit. It is negative when the project is
+	// over-committed
+	//	+optional
+	Remaining corev1.ResourceList `json:"remaining,omitempty"`
+
+	// NamespaceQuotas is the per-namespace breakdown of the project-quotas
+	//	+optional
+	NamespaceQuotas []NamespaceQuota `json:"namespaceQuotas,omitempty"`
+
+	// Children are the projects whose parent is this project
+	//	+optional
+	Children []string `json:"children,omitempty"`
+
+	// Carved is, for every project limit, the sum of the projectLimits of the
+	// children. Remaining accounts for it
+	//	+optional
+	Carved corev1.ResourceList `json:"carved,omitempty"`
+
+	// SubtreeAllocated is, for every project limit, the sum of the spec.hard
+	// of the quotas of the project and of all its descendants
+	//	+optional
+	SubtreeAllocated corev1.ResourceList `json:"subtreeAllocated,omitempty"`
+
+	// SubtreeUsed is, for every project limit, the sum of the status.used of
+	// the quotas of the project and of all its descendants
+	//	+optional
+	SubtreeUsed corev1.ResourceList `json:"subtreeUsed,omitempty"`
+
+	// Conditions are the latest observations of the project's state
+	//	+optional
+	Conditions []ProjectCondition `json:"conditions,omitempty"`
+}
+
+// Condition types maintained on a Project
+const (
+	// ProjectReady is true when every namespace of the project has its project-quota
+	ProjectReady = "Ready"
+	// ProjectOverCommitted is true when the project-quotas allocate more than the project limits
+	ProjectOverCommitted = "OverCommitted"
+	// ProjectNearLimits is true when the project-quotas use most of the project limits
+	ProjectNearLimits = "NearLimits"
+	// ProjectLimitsExceeded is true when the project-quotas use more than the project limits
+	ProjectLimitsExceeded = "LimitsExceeded"
+	// ProjectDeleting is true while the namespaces of a deleted project are
+	// handled according to its deletion policy
+	ProjectDeleting = "Deleting"
+)
+
+// NamespaceProjectNotFound is the namespace condition that is true when the
+// namespace's project label points at a missing Project
+const NamespaceProjectNotFound corev1.NamespaceConditionType = "ProjectNotFound"
+
+// NamespaceDefaultQuotaReduced is the namespace condition that is true when
+// the project's defaultNamespaceQuota did not fit in the remaining budget of
+// the project, and the namespace got a project-quota with no cpu and no memory
+const NamespaceDefaultQuotaReduced corev1.NamespaceConditionType = "DefaultQuotaReduced"
+
+// NamespaceProjectQuotaOverLimits is the namespace condition that is true when
+// the project-quota of the namespace takes part in pushing the project over
+// its limits, e.g. because it was changed while the webhook was not called
+const NamespaceProjectQuotaOverLimits corev1.NamespaceConditionType = "ProjectQuotaOverLimits"
+
+// NamespaceTemplateFailed is the namespace condition that is true when the
+// objects of the project's namespace template could not be stamped into the
+// namespace
+const NamespaceTemplateFailed corev1.NamespaceConditionType = "TemplateFailed"
+
+// ProjectQuotaManagedLabel marks the project-quota maintained by the operator
+const ProjectQuotaManagedLabel = "project.my.domain/managed"
+
+// DefaultQuotaHashAnnotation is the hash of the spec.hard of the project's
+// default quota a project-quota was last set to. A project-quota whose
+// spec.hard still has this hash follows the changes of the default quota
+const DefaultQuotaHashAnnotation = "project.my.domain/default-quota-hash"
+
+// ProjectCondition has the same shape as the upstream metav1.Condition
+type ProjectCondition struct {
+	// Type of the condition, in CamelCase
+	Type string `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown
+	Status metav1.ConditionStatus `json:"status"`
+
+	// ObservedGeneration is the .metadata.generation the condition was set for
+	//	+optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastTransitionTime is the last time the condition changed status
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// Reason is a CamelCase programmatic identifier for the last transition
+	Reason string `json:"reason"`
+
+	// Message is a human readable description of the last transition
+	//	+optional
+	Message string `json:"message,omitempty"`
+}
+
+// NamespaceQuota is the project-quota of one namespace of the project
+type NamespaceQuota struct {
+	// Name of the namespace
+	Name string `json:"name"`
+
+	// Hard is the spec.hard of the namespace's project-quota
+	//	+optional
+	Hard corev1.ResourceList `json:"hard,omitempty"`
+
+	// Used is the status.used of the namespace's project-quota
+	//	+optional
+	Used corev1.ResourceList `json:"used,omitempty"`
+}
+
+// DefaultQuotaSpec is the spec of the project-quota created in a new namespace
+// of a project without defaultNamespaceQuota: no cpu and no memory
+func DefaultQuotaSpec() corev1.ResourceQuotaSpec {
+	return corev1.ResourceQuotaSpec{
+		Hard: corev1.ResourceList{
+			corev1.ResourceCPU:    *resource.NewQuantity(0, resource.DecimalSI),
+			corev1.ResourceMemory: *resource.NewQuantity(0, resource.BinarySI),
+		},
+	}
+}
+
+// NamespaceQuotaSpec is the spec of the project-quota created in a new
+// namespace of the project
+func (p *Project) NamespaceQuotaSpec() corev1.ResourceQuotaSpec {
+	if p.Spec.DefaultNamespaceQuota == nil {
+		return DefaultQuotaSpec()
+	}
+	return *p.Spec.DefaultNamespaceQuota.DeepCopy()
 }
 
 // +kubebuilder:object:root=true
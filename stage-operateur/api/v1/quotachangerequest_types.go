@@ -0,0 +1,141 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// QuotaChangeRequestSpec defines the desired state of QuotaChangeRequest
+type QuotaChangeRequestSpec struct {
+	// Delta is added to the spec.hard of the project-quota of the namespace
+	// of the request. Negative amounts lower it
+	Delta corev1.ResourceList `json:"delta"`
+
+	// Reason tells why the change is needed
+	//	+optional
+	Reason string `json:"reason,omitempty"`
+
+	// Decision of a project admin on the request
+	//	+optional
+	Decision QuotaChangeDecision `json:"decision,omitempty"`
+}
+
+// QuotaChangeDecision is the decision of a project admin on a QuotaChangeRequest
+// +kubebuilder:validation:Enum=Approved;Rejected
+type QuotaChangeDecision string
+
+// Decisions a project admin can take on a QuotaChangeRequest
+const (
+	QuotaChangeDecisionApproved QuotaChangeDecision = "Approved"
+	QuotaChangeDecisionRejected QuotaChangeDecision = "Rejected"
+)
+
+// QuotaChangePhase is where a QuotaChangeRequest stands
+type QuotaChangePhase string
+
+const (
+	// QuotaChangePending is a request waiting for the decision of a project admin
+	QuotaChangePending QuotaChangePhase = "Pending"
+	// QuotaChangeInProgress is an approved request whose target spec.hard is
+	// recorded and being written to the project-quota
+	QuotaChangeInProgress QuotaChangePhase = "InProgress"
+	// QuotaChangeApplied is a request whose delta has been applied to the project-quota
+	QuotaChangeApplied QuotaChangePhase = "Applied"
+	// QuotaChangeRejected is a request a project admin rejected
+	QuotaChangeRejected QuotaChangePhase = "Rejected"
+	// QuotaChangeFailed is an approved request that could not be applied, the
+	// message tells why
+	QuotaChangeFailed QuotaChangePhase = "Failed"
+)
+
+// QuotaChangeRequestStatus defines the observed state of QuotaChangeRequest
+type QuotaChangeRequestStatus struct {
+	// Phase of the request: Pending, InProgress, Applied, Rejected or Failed
+	//	+optional
+	Phase QuotaChangePhase `json:"phase,omitempty"`
+
+	// Message is a human readable description of the phase
+	//	+optional
+	Message string `json:"message,omitempty"`
+
+	// AppliedHard is the spec.hard of the project-quota once the delta applied,
+	// recorded before the project-quota is updated
+	//	+optional
+	AppliedHard corev1.ResourceList `json:"appliedHard,omitempty"`
+
+	// BaseHard is the spec.hard of the project-quota the delta was added to,
+	// recorded with AppliedHard
+	//	+optional
+	BaseHard corev1.ResourceList `json:"baseHard,omitempty"`
+
+	// History records every step of the request, for audit
+	//	+optional
+	History []QuotaChangeRecord `json:"history,omitempty"`
+}
+
+// QuotaChangeRecord is one step of a QuotaChangeRequest
+type QuotaChangeRecord struct {
+	// Time of the step
+	Time metav1.Time `json:"time"`
+
+	// Action taken: Requested, Approved, AutoApproved, Rejected, Applied or Failed
+	Action string `json:"action"`
+
+	// User who took the action, empty for the operator
+	//	+optional
+	User string `json:"user,omitempty"`
+
+	// Message is a human readable description of the step
+	//	+optional
+	Message string `json:"message,omitempty"`
+}
+
+// Annotations recording the users behind a QuotaChangeRequest, set by its
+// admission webhook
+const (
+	QuotaChangeRequestedByAnnotation = "project.my.domain/requested-by"
+	QuotaChangeDecidedByAnnotation   = "project.my.domain/decided-by"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.spec.reason`
+// QuotaChangeRequest asks for a change of the project-quota of the namespace
+// it is created in
+type QuotaChangeRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   QuotaChangeRequestSpec   `json:"spec,omitempty"`
+	Status QuotaChangeRequestStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// QuotaChangeRequestList contains a list of QuotaChangeRequest
+type QuotaChangeRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []QuotaChangeRequest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&QuotaChangeRequest{}, &QuotaChangeRequestList{})
+}
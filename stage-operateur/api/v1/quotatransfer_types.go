@@ -0,0 +1,121 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// QuotaTransferSpec defines the desired state of QuotaTransfer
+type QuotaTransferSpec struct {
+	// To is the namespace, of the same project, whose project-quota receives
+	// the amount
+	To string `json:"to"`
+
+	// Amount is taken from the spec.hard of the project-quota of the
+	// namespace of the transfer and added to the one of the To namespace
+	Amount corev1.ResourceList `json:"amount"`
+
+	// Reason tells why the transfer is needed
+	//	+optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// QuotaTransferPhase is where a QuotaTransfer stands
+type QuotaTransferPhase string
+
+const (
+	// QuotaTransferInProgress is a transfer whose project-quotas are being
+	// updated. A transfer found in this phase is Applied when both
+	// project-quotas are as after it, has the raise of the receiving one
+	// retried when only the giving one was lowered, and is rolled back
+	// otherwise
+	QuotaTransferInProgress QuotaTransferPhase = "InProgress"
+	// QuotaTransferApplied is a transfer applied to both project-quotas
+	QuotaTransferApplied QuotaTransferPhase = "Applied"
+	// QuotaTransferFailed is a transfer that could not be applied, the
+	// project-quotas are left or put back as they were. The message tells why
+	QuotaTransferFailed QuotaTransferPhase = "Failed"
+)
+
+// QuotaTransferStatus defines the observed state of QuotaTransfer
+type QuotaTransferStatus struct {
+	// Phase of the transfer: InProgress, Applied or Failed
+	//	+optional
+	Phase QuotaTransferPhase `json:"phase,omitempty"`
+
+	// Message is a human readable description of the phase
+	//	+optional
+	Message string `json:"message,omitempty"`
+
+	// From is the namespace whose project-quota gives the amount, recorded
+	// when the transfer starts
+	//	+optional
+	From string `json:"from,omitempty"`
+
+	// To is the namespace whose project-quota receives the amount, recorded
+	// when the transfer starts so that a later change of spec.to does not
+	// redirect a rollback
+	//	+optional
+	To string `json:"to,omitempty"`
+
+	// Before is the spec.hard of both project-quotas before the transfer,
+	// restored when it fails
+	//	+optional
+	Before *QuotaTransferHard `json:"before,omitempty"`
+
+	// After is the spec.hard of both project-quotas once the transfer applied
+	//	+optional
+	After *QuotaTransferHard `json:"after,omitempty"`
+}
+
+// QuotaTransferHard is the spec.hard of the project-quotas of a transfer
+type QuotaTransferHard struct {
+	// From is the spec.hard of the project-quota giving the amount
+	From corev1.ResourceList `json:"from"`
+
+	// To is the spec.hard of the project-quota receiving the amount
+	To corev1.ResourceList `json:"to"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="To",type=string,JSONPath=`.spec.to`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// QuotaTransfer moves an amount from the project-quota of the namespace it is
+// created in to the project-quota of another namespace of the same project
+type QuotaTransfer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   QuotaTransferSpec   `json:"spec,omitempty"`
+	Status QuotaTransferStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// QuotaTransferList contains a list of QuotaTransfer
+type QuotaTransferList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []QuotaTransfer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&QuotaTransfer{}, &QuotaTransferList{})
+}
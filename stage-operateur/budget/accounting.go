@@ -0,0 +1,107 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package budget
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+
+	projectv1 "project/api/v1"
+)
+
+// AccountingMode selects which ResourceQuotas of a project namespace count
+// against the project limits.
+type AccountingMode string
+
+const (
+	// ManagedQuota only counts the project-quota of each namespace. Other
+	// ResourceQuotas are left alone.
+	ManagedQuota AccountingMode = "ManagedQuota"
+	// AllQuotas counts every ResourceQuota of each namespace, keeping the
+	// tightest hard limit of every resource.
+	AllQuotas AccountingMode = "AllQuotas"
+	// Exclusive only counts the project-quota and refuses any other
+	// ResourceQuota in project namespaces.
+	Exclusive AccountingMode = "Exclusive"
+)
+
+// ParseAccountingMode validates an accounting mode. The empty string selects
+// ManagedQuota.
+func ParseAccountingMode(value string) (AccountingMode, error) {
+	switch mode := AccountingMode(value); mode {
+	case "":
+		return ManagedQuota, nil
+	case ManagedQuota, AllQuotas, Exclusive:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown accounting mode %q, expected one of %s, %s, %s", value, ManagedQuota, AllQuotas, Exclusive)
+	}
+}
+
+// Counts reports whether a ResourceQuota with the given name counts against
+// the project limits.
+func (m AccountingMode) Counts(names projectv1.Names, quotaName string) bool {
+	return m == AllQuotas || quotaName == names.QuotaName()
+}
+
+// Effective merges the ResourceQuotas of one namespace into the quota that
+// binds it: the tightest hard limit and the highest usage of every resource.
+// A single quota is returned unchanged.
+func Effective(quotas []corev1.ResourceQuota) corev1.ResourceQuota {
+	if len(quotas) == 1 {
+		return quotas[0]
+	}
+	effective := corev1.ResourceQuota{}
+	effective.Spec.Hard = corev1.ResourceList{}
+	effective.Status.Used = corev1.ResourceList{}
+	for _, quota := range quotas {
+		effective.Namespace = quota.Namespace
+		for name, hard := range quota.Spec.Hard {
+			if current, ok := effective.Spec.Hard[name]; !ok || hard.Cmp(current) < 0 {
+				effective.Spec.Hard[name] = hard.DeepCopy()
+			}
+		}
+		for name, used := range quota.Status.Used {
+			if current, ok := effective.Status.Used[name]; !ok || used.Cmp(current) > 0 {
+				effective.Status.Used[name] = used.DeepCopy()
+			}
+		}
+	}
+	return effective
+}
+
+// PerNamespace merges the quotas of every namespace with Effective and
+// returns one quota per namespace, sorted by namespace.
+func PerNamespace(quotas []corev1.ResourceQuota) []corev1.ResourceQuota {
+	byNamespace := map[string][]corev1.ResourceQuota{}
+	for _, quota := range quotas {
+		byNamespace[quota.Namespace] = append(byNamespace[quota.Namespace], quota)
+	}
+	namespaces := make([]string, 0, len(byNamespace))
+	for namespace := range byNamespace {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	effective := make([]corev1.ResourceQuota, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		effective = append(effective, Effective(byNamespace[namespace]))
+	}
+	return effective
+}
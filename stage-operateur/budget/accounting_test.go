@@ -0,0 +1,83 @@
+package budget
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	projectv1 "project/api/v1"
+)
+
+var _ = Describe("ParseAccountingMode", func() {
+	It("should default to ManagedQuota", func() {
+		// When
+		mode, err := ParseAccountingMode("")
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mode).To(Equal(ManagedQuota))
+	})
+
+	It("should reject unknown modes", func() {
+		// When
+		_, err := ParseAccountingMode("Everything")
+
+		// Then
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("AccountingMode.Counts", func() {
+	It("should only count the project-quota unless every quota is counted", func() {
+		Expect(ManagedQuota.Counts(projectv1.Names{}, "project-quota")).To(BeTrue())
+		Expect(ManagedQuota.Counts(projectv1.Names{}, "scoped")).To(BeFalse())
+		Expect(Exclusive.Counts(projectv1.Names{}, "scoped")).To(BeFalse())
+		Expect(AllQuotas.Counts(projectv1.Names{}, "scoped")).To(BeTrue())
+	})
+
+	It("should count the configured project-quota name", func() {
+		names := projectv1.Names{ProjectQuotaName: "team-quota"}
+		Expect(ManagedQuota.Counts(names, "team-quota")).To(BeTrue())
+		Expect(ManagedQuota.Counts(names, "project-quota")).To(BeFalse())
+	})
+})
+
+var _ = Describe("PerNamespace", func() {
+	newQuota := func(namespace string, name string, hard corev1.ResourceList, used corev1.ResourceList) corev1.ResourceQuota {
+		return corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       corev1.ResourceQuotaSpec{Hard: hard},
+			Status:     corev1.ResourceQuotaStatus{Used: used},
+		}
+	}
+
+	It("should keep the tightest hard limit and the highest usage of every namespace", func() {
+		// Given
+		quotas := []corev1.ResourceQuota{
+			newQuota("test2", "project-quota", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")}, nil),
+			newQuota("test1", "project-quota",
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourcePods: resource.MustParse("10")},
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}),
+			newQuota("test1", "scoped",
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1500m")}),
+		}
+
+		// When
+		effective := PerNamespace(quotas)
+
+		// Then
+		Expect(effective).To(HaveLen(2))
+		Expect(effective[0].Namespace).To(Equal("test1"))
+		cpu := effective[0].Spec.Hard[corev1.ResourceCPU]
+		pods := effective[0].Spec.Hard[corev1.ResourcePods]
+		used := effective[0].Status.Used[corev1.ResourceCPU]
+		Expect(cpu.Cmp(resource.MustParse("2"))).To(Equal(0))
+		Expect(pods.Cmp(resource.MustParse("10"))).To(Equal(0))
+		Expect(used.Cmp(resource.MustParse("1500m"))).To(Equal(0))
+		Expect(effective[1]).To(Equal(quotas[0]))
+	})
+})
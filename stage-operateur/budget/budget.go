@@ -0,0 +1,278 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package budget accounts the ResourceQuotas of a project's namespaces
+// against the limits declared in Project.Spec.ProjectLimits.
+package budget
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/inf.v0"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// aliases maps project limit names to the other ResourceQuota names granting
+// the same resource, in the order they are read when the quota does not name
+// the limit itself. Kubernetes treats a short cpu, memory or ephemeral-storage
+// name and its requests.* name as the same resource, so each stands for the
+// other. Both also count against the limits.* project limit, which a
+// container never requests more than: quotas created by the operator use the
+// short names, which have always been counted there.
+var aliases = map[corev1.ResourceName][]corev1.ResourceName{
+	corev1.ResourceCPU:                      {corev1.ResourceRequestsCPU},
+	corev1.ResourceMemory:                   {corev1.ResourceRequestsMemory},
+	corev1.ResourceEphemeralStorage:         {corev1.ResourceRequestsEphemeralStorage},
+	corev1.ResourceRequestsCPU:              {corev1.ResourceCPU},
+	corev1.ResourceRequestsMemory:           {corev1.ResourceMemory},
+	corev1.ResourceRequestsEphemeralStorage: {corev1.ResourceEphemeralStorage},
+	corev1.ResourceLimitsCPU:                {corev1.ResourceCPU, corev1.ResourceRequestsCPU},
+	corev1.ResourceLimitsMemory:             {corev1.ResourceMemory, corev1.ResourceRequestsMemory},
+	corev1.ResourceLimitsEphemeralStorage:   {corev1.ResourceEphemeralStorage, corev1.ResourceRequestsEphemeralStorage},
+}
+
+// Amount returns the quantity granted by a ResourceQuota hard spec for the
+// given project limit name, read from its aliases when the quota does not
+// name it. Extended resources such as nvidia.com/gpu can only be requested in
+// a ResourceQuota, so they are read from requests.<name>. A resource that is
+// not granted at all counts as zero.
+func Amount(hard corev1.ResourceList, name corev1.ResourceName) resource.Quantity {
+	if quantity, ok := hard[name]; ok {
+		return quantity.DeepCopy()
+	}
+	if quantity, ok := aliasAmount(hard, name); ok {
+		return quantity
+	}
+	if isExtendedResourceName(name) {
+		if quantity, ok := hard[corev1.DefaultResourceRequestsPrefix+name]; ok {
+			return quantity.DeepCopy()
+		}
+	}
+	return resource.Quantity{Format: resource.DecimalSI}
+}
+
+// aliasAmount returns the quantity granted for the project limit name by the
+// first of its aliases the hard spec names.
+func aliasAmount(hard corev1.ResourceList, name corev1.ResourceName) (resource.Quantity, bool) {
+	for _, alias := range aliases[name] {
+		if quantity, ok := hard[alias]; ok {
+			return quantity.DeepCopy(), true
+		}
+	}
+	return resource.Quantity{}, false
+}
+
+// Sum returns the total quantity granted for the given project limit name
+// by all the quotas.
+func Sum(quotas []corev1.ResourceQuota, name corev1.ResourceName) resource.Quantity {
+	lists := make([]corev1.ResourceList, 0, len(quotas))
+	for _, quota := range quotas {
+		lists = append(lists, quota.Spec.Hard)
+	}
+	return sum(lists, name)
+}
+
+// Allocated returns, for every project limit, the total granted by the
+// spec.hard of the quotas.
+func Allocated(limits corev1.ResourceList, quotas []corev1.ResourceQuota) corev1.ResourceList {
+	allocated := corev1.ResourceList{}
+	for name := range limits {
+		allocated[name] = Sum(quotas, name)
+	}
+	return allocated
+}
+
+// Used returns, for every project limit, the total consumed according to the
+// status.used of the quotas.
+func Used(limits corev1.ResourceList, quotas []corev1.ResourceQuota) corev1.ResourceList {
+	lists := make([]corev1.ResourceList, 0, len(quotas))
+	for _, quota := range quotas {
+		lists = append(lists, quota.Status.Used)
+	}
+	used := corev1.ResourceList{}
+	for name := range limits {
+		used[name] = sum(lists, name)
+	}
+	return used
+}
+
+// Remaining returns, for every project limit, the limit minus the allocated
+// amount. The result is negative for an over-committed resource.
+func Remaining(limits corev1.ResourceList, allocated corev1.ResourceList) corev1.ResourceList {
+	remaining := corev1.ResourceList{}
+	for name, limit := range limits {
+		left := limit.DeepCopy()
+		left.Sub(allocated[name])
+		remaining[name] = left
+	}
+	return remaining
+}
+
+// Increased returns the sorted names of the project limits for which newHard
+// grants more than oldHard.
+func Increased(limits corev1.ResourceList, oldHard corev1.ResourceList, newHard corev1.ResourceList) []corev1.ResourceName {
+	var names []corev1.ResourceName
+	for name := range limits {
+		oldAmount := Amount(oldHard, name)
+		newAmount := Amount(newHard, name)
+		if newAmount.Cmp(oldAmount) > 0 {
+			names = append(names, name)
+		}
+	}
+	return sorted(names)
+}
+
+// Exceeded returns the sorted names of the project limits that the quotas
+// add up to more than.
+func Exceeded(limits corev1.ResourceList, quotas []corev1.ResourceQuota) []corev1.ResourceName {
+	return Above(limits, Allocated(limits, quotas))
+}
+
+// Overrun returns the sorted names of the project limits that the quotas add
+// up to more than and that growing a quota from oldHard to newHard
+// increases. A project already over a limit can still shrink.
+func Overrun(limits corev1.ResourceList, quotas []corev1.ResourceQuota, oldHard corev1.ResourceList, newHard corev1.ResourceList) []corev1.ResourceName {
+	increased := map[corev1.ResourceName]bool{}
+	for _, name := range Increased(limits, oldHard, newHard) {
+		increased[name] = true
+	}
+	var names []corev1.ResourceName
+	for _, name := range Exceeded(limits, quotas) {
+		if increased[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Above returns the sorted names of the project limits that amounts, keyed by
+// project limit name, are greater than.
+func Above(limits corev1.ResourceList, amounts corev1.ResourceList) []corev1.ResourceName {
+	var names []corev1.ResourceName
+	for name, limit := range limits {
+		amount := amounts[name]
+		if amount.Cmp(limit) > 0 {
+			names = append(names, name)
+		}
+	}
+	return sorted(names)
+}
+
+// Reaching returns the sorted names of the project limits that amounts, keyed
+// by project limit name, reach at least percent of. Resources with no amount
+// never reach their limit.
+func Reaching(limits corev1.ResourceList, amounts corev1.ResourceList, percent int64) []corev1.ResourceName {
+	var names []corev1.ResourceName
+	for name, limit := range limits {
+		amount := amounts[name]
+		if amount.Sign() <= 0 {
+			continue
+		}
+		scaledAmount := new(inf.Dec).Mul(amount.AsDec(), inf.NewDec(100, 0))
+		scaledLimit := new(inf.Dec).Mul(limit.AsDec(), inf.NewDec(percent, 0))
+		if scaledAmount.Cmp(scaledLimit) >= 0 {
+			names = append(names, name)
+		}
+	}
+	return sorted(names)
+}
+
+// Join formats resource names for admission and status messages.
+func Join(names []corev1.ResourceName) string {
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, string(name))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// standardNames are the resource names a ResourceQuota can limit without a
+// prefix or a domain.
+var standardNames = map[corev1.ResourceName]bool{
+	corev1.ResourceCPU:                      true,
+	corev1.ResourceMemory:                   true,
+	corev1.ResourceEphemeralStorage:         true,
+	corev1.ResourceRequestsCPU:              true,
+	corev1.ResourceRequestsMemory:           true,
+	corev1.ResourceRequestsStorage:          true,
+	corev1.ResourceRequestsEphemeralStorage: true,
+	corev1.ResourceLimitsCPU:                true,
+	corev1.ResourceLimitsMemory:             true,
+	corev1.ResourceLimitsEphemeralStorage:   true,
+	corev1.ResourcePods:                     true,
+	corev1.ResourceServices:                 true,
+	corev1.ResourceServicesNodePorts:        true,
+	corev1.ResourceServicesLoadBalancers:    true,
+	corev1.ResourceReplicationControllers:   true,
+	corev1.ResourceQuotas:                   true,
+	corev1.ResourceSecrets:                  true,
+	corev1.ResourceConfigMaps:               true,
+	corev1.ResourcePersistentVolumeClaims:   true,
+}
+
+// Supported reports whether name can be used as a project limit: a standard
+// quota resource, an object count (count/<resource>), huge pages, a storage
+// class resource or an extended resource.
+func Supported(name corev1.ResourceName) bool {
+	value := string(name)
+	switch {
+	case standardNames[name]:
+		return true
+	case strings.HasPrefix(value, "count/") && len(value) > len("count/"):
+		return true
+	case strings.HasPrefix(value, corev1.ResourceHugePagesPrefix) || strings.HasPrefix(value, corev1.ResourceRequestsHugePagesPrefix):
+		return true
+	case strings.HasSuffix(value, ".storageclass.storage.k8s.io/"+string(corev1.ResourceRequestsStorage)) ||
+		strings.HasSuffix(value, ".storageclass.storage.k8s.io/"+string(corev1.ResourcePersistentVolumeClaims)):
+		return true
+	case strings.HasPrefix(value, corev1.DefaultResourceRequestsPrefix):
+		return isExtendedResourceName(corev1.ResourceName(strings.TrimPrefix(value, corev1.DefaultResourceRequestsPrefix)))
+	default:
+		return isExtendedResourceName(name)
+	}
+}
+
+// isExtendedResourceName reports whether name is a fully-qualified resource
+// name outside of the kubernetes.io domain, such as nvidia.com/gpu.
+func isExtendedResourceName(name corev1.ResourceName) bool {
+	value := string(name)
+	if strings.HasPrefix(value, corev1.DefaultResourceRequestsPrefix) ||
+		strings.HasPrefix(value, "limits.") ||
+		strings.HasPrefix(value, "count/") {
+		return false
+	}
+	return strings.Contains(value, "/") && !strings.Contains(value, "kubernetes.io/")
+}
+
+func sum(lists []corev1.ResourceList, name corev1.ResourceName) resource.Quantity {
+	total := resource.Quantity{Format: resource.DecimalSI}
+	for i, list := range lists {
+		amount := Amount(list, name)
+		if i == 0 {
+			total = amount
+			continue
+		}
+		total.Add(amount)
+	}
+	return total
+}
+
+func sorted(names []corev1.ResourceName) []corev1.ResourceName {
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,332 @@
+package budget
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func quotaWithHard(hard corev1.ResourceList) corev1.ResourceQuota {
+	return corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{Hard: hard}}
+}
+
+var _ = Describe("Amount", func() {
+	It("should read the exact resource name", func() {
+		// Given
+		hard := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2"), corev1.ResourceCPU: resource.MustParse("1")}
+
+		// When
+		amount := Amount(hard, corev1.ResourceLimitsCPU)
+
+		// Then
+		Expect(amount.Cmp(resource.MustParse("2"))).To(Equal(0))
+	})
+
+	It("should fall back to the short name for cpu and memory", func() {
+		// Given
+		hard := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("1Gi")}
+
+		// When
+		cpu := Amount(hard, corev1.ResourceLimitsCPU)
+		memory := Amount(hard, corev1.ResourceRequestsMemory)
+
+		// Then
+		Expect(cpu.Cmp(resource.MustParse("500m"))).To(Equal(0))
+		Expect(memory.Cmp(resource.MustParse("1Gi"))).To(Equal(0))
+	})
+
+	It("should read the requests.* name for a short cpu or memory limit", func() {
+		// Given
+		hard := corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("3"), corev1.ResourceRequestsMemory: resource.MustParse("2Gi")}
+
+		// When
+		cpu := Amount(hard, corev1.ResourceCPU)
+		memory := Amount(hard, corev1.ResourceMemory)
+
+		// Then
+		Expect(cpu.Cmp(resource.MustParse("3"))).To(Equal(0))
+		Expect(memory.Cmp(resource.MustParse("2Gi"))).To(Equal(0))
+	})
+
+	It("should count requests.* cpu and memory against a limits.* limit like the short names", func() {
+		// Given
+		hard := corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("3"), corev1.ResourceRequestsMemory: resource.MustParse("2Gi")}
+
+		// When
+		cpu := Amount(hard, corev1.ResourceLimitsCPU)
+		memory := Amount(hard, corev1.ResourceLimitsMemory)
+
+		// Then
+		Expect(cpu.Cmp(resource.MustParse("3"))).To(Equal(0))
+		Expect(memory.Cmp(resource.MustParse("2Gi"))).To(Equal(0))
+	})
+
+	It("should read extended resources from their requests name", func() {
+		// Given
+		hard := corev1.ResourceList{"requests.nvidia.com/gpu": resource.MustParse("4")}
+
+		// When
+		amount := Amount(hard, "nvidia.com/gpu")
+
+		// Then
+		Expect(amount.Cmp(resource.MustParse("4"))).To(Equal(0))
+	})
+
+	It("should count a resource that is not granted as zero", func() {
+		// When
+		amount := Amount(corev1.ResourceList{}, "count/deployments.apps")
+
+		// Then
+		Expect(amount.IsZero()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Sum", func() {
+	It("should add millicores and mixed memory units exactly", func() {
+		// Given
+		quotas := []corev1.ResourceQuota{
+			quotaWithHard(corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m"), corev1.ResourceMemory: resource.MustParse("512Mi")}),
+			quotaWithHard(corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1250m"), corev1.ResourceMemory: resource.MustParse("536870912")}),
+		}
+
+		// When
+		cpu := Sum(quotas, corev1.ResourceLimitsCPU)
+		memory := Sum(quotas, corev1.ResourceLimitsMemory)
+
+		// Then
+		Expect(cpu.MilliValue()).To(Equal(int64(1500)))
+		Expect(memory.Cmp(resource.MustParse("1Gi"))).To(Equal(0))
+	})
+
+	It("should return zero for no quotas", func() {
+		// When
+		sum := Sum(nil, corev1.ResourcePods)
+
+		// Then
+		Expect(sum.IsZero()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Exceeded and Increased", func() {
+	limits := corev1.ResourceList{
+		corev1.ResourcePods:      resource.MustParse("10"),
+		corev1.ResourceLimitsCPU: resource.MustParse("1"),
+	}
+
+	It("should only report the limits that the quotas add up to more than", func() {
+		// Given
+		quotas := []corev1.ResourceQuota{
+			quotaWithHard(corev1.ResourceList{corev1.ResourcePods: resource.MustParse("6"), corev1.ResourceCPU: resource.MustParse("500m")}),
+			quotaWithHard(corev1.ResourceList{corev1.ResourcePods: resource.MustParse("6"), corev1.ResourceCPU: resource.MustParse("500m")}),
+		}
+
+		// When
+		exceeded := Exceeded(limits, quotas)
+
+		// Then
+		Expect(exceeded).To(Equal([]corev1.ResourceName{corev1.ResourcePods}))
+	})
+
+	It("should count requests.* quotas against a short cpu limit", func() {
+		// Given
+		shortLimits := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}
+		quotas := []corev1.ResourceQuota{
+			quotaWithHard(corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("600m")}),
+			quotaWithHard(corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("600m")}),
+		}
+
+		// When
+		exceeded := Exceeded(shortLimits, quotas)
+
+		// Then
+		Expect(exceeded).To(Equal([]corev1.ResourceName{corev1.ResourceCPU}))
+	})
+
+	It("should only report the limited resources that increase", func() {
+		// Given
+		oldHard := corev1.ResourceList{corev1.ResourcePods: resource.MustParse("6"), corev1.ResourceCPU: resource.MustParse("500m")}
+		newHard := corev1.ResourceList{corev1.ResourcePods: resource.MustParse("5"), corev1.ResourceCPU: resource.MustParse("600m"), corev1.ResourceServices: resource.MustParse("3")}
+
+		// When
+		increased := Increased(limits, oldHard, newHard)
+
+		// Then
+		Expect(increased).To(Equal([]corev1.ResourceName{corev1.ResourceLimitsCPU}))
+	})
+})
+
+var _ = Describe("Reaching", func() {
+	limits := corev1.ResourceList{
+		corev1.ResourceLimitsCPU:    resource.MustParse("1"),
+		corev1.ResourceLimitsMemory: resource.MustParse("1Gi"),
+		corev1.ResourcePods:         resource.MustParse("0"),
+	}
+
+	It("should report the limits that amounts reach the given share of", func() {
+		// Given
+		amounts := corev1.ResourceList{
+			corev1.ResourceLimitsCPU:    resource.MustParse("900m"),
+			corev1.ResourceLimitsMemory: resource.MustParse("900Mi"),
+		}
+
+		// When
+		reaching := Reaching(limits, amounts, 90)
+
+		// Then
+		Expect(reaching).To(Equal([]corev1.ResourceName{corev1.ResourceLimitsCPU}))
+	})
+
+	It("should not report limits without any amount", func() {
+		// When
+		reaching := Reaching(limits, corev1.ResourceList{corev1.ResourcePods: resource.MustParse("0")}, 90)
+
+		// Then
+		Expect(reaching).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Carved", func() {
+	It("should sum the limits of the children that set them", func() {
+		// Given
+		limits := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("10"), corev1.ResourcePods: resource.MustParse("100")}
+		children := []corev1.ResourceList{
+			{corev1.ResourceLimitsCPU: resource.MustParse("2")},
+			{corev1.ResourceLimitsCPU: resource.MustParse("500m"), corev1.ResourcePods: resource.MustParse("10")},
+		}
+
+		// When
+		carved := Carved(limits, children)
+
+		// Then
+		cpu := carved[corev1.ResourceLimitsCPU]
+		pods := carved[corev1.ResourcePods]
+		Expect(cpu.Cmp(resource.MustParse("2500m"))).To(Equal(0))
+		Expect(pods.Cmp(resource.MustParse("10"))).To(Equal(0))
+	})
+})
+
+var _ = Describe("Overrun", func() {
+	limits := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("10"), corev1.ResourcePods: resource.MustParse("10")}
+	quotas := []corev1.ResourceQuota{
+		quotaWithHard(corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("12"), corev1.ResourcePods: resource.MustParse("12")}),
+	}
+
+	It("should only report the exceeded limits that grow", func() {
+		// Given
+		oldHard := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("11"), corev1.ResourcePods: resource.MustParse("12")}
+		newHard := quotas[0].Spec.Hard
+
+		// Then
+		Expect(Overrun(limits, quotas, oldHard, newHard)).To(Equal([]corev1.ResourceName{corev1.ResourceLimitsCPU}))
+	})
+})
+
+var _ = Describe("Allocatable", func() {
+	limits := corev1.ResourceList{
+		corev1.ResourceLimitsCPU:      resource.MustParse("10"),
+		corev1.ResourceLimitsMemory:   resource.MustParse("8Gi"),
+		corev1.ResourceRequestsCPU:    resource.MustParse("4"),
+		corev1.ResourceRequestsMemory: resource.MustParse("4Gi"),
+	}
+
+	It("should multiply the limits.* limits by their factor", func() {
+		// When
+		allocatable := Allocatable(limits, corev1.ResourceList{
+			corev1.ResourceLimitsCPU:    resource.MustParse("1.5"),
+			corev1.ResourceLimitsMemory: resource.MustParse("2"),
+		})
+
+		// Then
+		Expect(quantity(allocatable, corev1.ResourceLimitsCPU).Cmp(resource.MustParse("15"))).To(Equal(0))
+		Expect(quantity(allocatable, corev1.ResourceLimitsMemory).Cmp(resource.MustParse("16Gi"))).To(Equal(0))
+		Expect(quantity(allocatable, corev1.ResourceRequestsCPU).Cmp(resource.MustParse("4"))).To(Equal(0))
+	})
+
+	It("should keep requests.* strictly capped", func() {
+		// When
+		allocatable := Allocatable(limits, corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("2")})
+
+		// Then
+		Expect(allocatable).To(Equal(limits))
+	})
+
+	It("should ignore factors below 1 and resources without limit", func() {
+		// When
+		allocatable := Allocatable(limits, corev1.ResourceList{
+			corev1.ResourceLimitsCPU:              resource.MustParse("500m"),
+			corev1.ResourceLimitsEphemeralStorage: resource.MustParse("2"),
+		})
+
+		// Then
+		Expect(allocatable).To(Equal(limits))
+	})
+
+	It("should round down to the milli unit", func() {
+		// When
+		allocatable := Allocatable(corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1")},
+			corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1.0005")})
+
+		// Then
+		Expect(quantity(allocatable, corev1.ResourceLimitsCPU).String()).To(Equal("1"))
+	})
+})
+
+var _ = Describe("Overcommitted", func() {
+	limits := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("10")}
+	overcommit := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2")}
+
+	It("should not overcommit a short cpu counted against an overcommitted limits.cpu", func() {
+		// Given
+		quotas := []corev1.ResourceQuota{quotaWithHard(corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("12")})}
+
+		// When
+		counted := Overcommitted(quotas, overcommit)
+
+		// Then
+		Expect(quantity(counted[0].Spec.Hard, corev1.ResourceLimitsCPU).Cmp(resource.MustParse("24"))).To(Equal(0))
+		Expect(Exceeded(Allocatable(limits, overcommit), counted)).To(Equal([]corev1.ResourceName{corev1.ResourceLimitsCPU}))
+		Expect(quotas[0].Spec.Hard).NotTo(HaveKey(corev1.ResourceLimitsCPU))
+	})
+
+	It("should not overcommit a requests.cpu counted against an overcommitted limits.cpu", func() {
+		// Given
+		quotas := []corev1.ResourceQuota{quotaWithHard(corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("12")})}
+
+		// When
+		counted := Overcommitted(quotas, overcommit)
+
+		// Then
+		Expect(quantity(counted[0].Spec.Hard, corev1.ResourceLimitsCPU).Cmp(resource.MustParse("24"))).To(Equal(0))
+		Expect(Exceeded(Allocatable(limits, overcommit), counted)).To(Equal([]corev1.ResourceName{corev1.ResourceLimitsCPU}))
+	})
+
+	It("should let a short cpu use the limit itself", func() {
+		// Given
+		quotas := []corev1.ResourceQuota{quotaWithHard(corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10")})}
+
+		// Then
+		Expect(Exceeded(Allocatable(limits, overcommit), Overcommitted(quotas, overcommit))).To(BeEmpty())
+	})
+
+	It("should overcommit the quotas naming limits.cpu", func() {
+		// Given
+		quotas := []corev1.ResourceQuota{quotaWithHard(corev1.ResourceList{
+			corev1.ResourceLimitsCPU: resource.MustParse("12"),
+			corev1.ResourceCPU:       resource.MustParse("6"),
+		})}
+
+		// When
+		counted := Overcommitted(quotas, overcommit)
+
+		// Then
+		Expect(counted).To(Equal(quotas))
+		Expect(Exceeded(Allocatable(limits, overcommit), counted)).To(BeEmpty())
+	})
+})
+
+func quantity(list corev1.ResourceList, name corev1.ResourceName) *resource.Quantity {
+	amount := list[name]
+	return &amount
+}
@@ -0,0 +1,71 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package budget
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NodeAllocatable sums the allocatable resources of the schedulable nodes,
+// under the project limit names that request them: cpu, memory, ephemeral
+// storage, hugepages and extended resources under both their short and
+// requests.* names, and pods. limits.* resources are left out, they are
+// usually set above what the nodes provide.
+func NodeAllocatable(nodes []corev1.Node) corev1.ResourceList {
+	capacity := corev1.ResourceList{}
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		for name, quantity := range node.Status.Allocatable {
+			for _, limitName := range requestNames(name) {
+				total := capacity[limitName]
+				total.Add(quantity)
+				capacity[limitName] = total
+			}
+		}
+	}
+	return capacity
+}
+
+// requestNames returns the project limit names that request the node
+// resource, none for a resource that cannot be requested by a quota.
+func requestNames(name corev1.ResourceName) []corev1.ResourceName {
+	switch {
+	case name == corev1.ResourcePods:
+		return []corev1.ResourceName{name}
+	case name == corev1.ResourceCPU || name == corev1.ResourceMemory || name == corev1.ResourceEphemeralStorage,
+		strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix), isExtendedResourceName(name):
+		return []corev1.ResourceName{name, corev1.DefaultResourceRequestsPrefix + name}
+	}
+	return nil
+}
+
+// ClusterCapacity is the capacity set explicitly, completed by the node
+// allocatable resources it does not name.
+func ClusterCapacity(explicit corev1.ResourceList, nodeAllocatable corev1.ResourceList) corev1.ResourceList {
+	capacity := nodeAllocatable.DeepCopy()
+	if capacity == nil {
+		capacity = corev1.ResourceList{}
+	}
+	for name, quantity := range explicit {
+		capacity[name] = quantity.DeepCopy()
+	}
+	return capacity
+}
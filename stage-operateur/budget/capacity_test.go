@@ -0,0 +1,56 @@
+package budget
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var _ = Describe("NodeAllocatable", func() {
+	newNode := func(cpu string, memory string, unschedulable bool) corev1.Node {
+		return corev1.Node{
+			Spec: corev1.NodeSpec{Unschedulable: unschedulable},
+			Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+				corev1.ResourcePods:   resource.MustParse("110"),
+				"nvidia.com/gpu":      resource.MustParse("1"),
+			}},
+		}
+	}
+
+	It("should sum the schedulable nodes under the project limit names requesting them", func() {
+		// Given
+		nodes := []corev1.Node{newNode("4", "16Gi", false), newNode("3500m", "8Gi", false), newNode("8", "32Gi", true)}
+
+		// When
+		capacity := NodeAllocatable(nodes)
+
+		// Then
+		Expect(capacity).To(HaveLen(7))
+		Expect(quantity(capacity, corev1.ResourceRequestsCPU).Cmp(resource.MustParse("7500m"))).To(Equal(0))
+		Expect(quantity(capacity, corev1.ResourceCPU).Cmp(resource.MustParse("7500m"))).To(Equal(0))
+		Expect(quantity(capacity, corev1.ResourceRequestsMemory).Cmp(resource.MustParse("24Gi"))).To(Equal(0))
+		Expect(quantity(capacity, corev1.ResourcePods).Cmp(resource.MustParse("220"))).To(Equal(0))
+		Expect(quantity(capacity, "requests.nvidia.com/gpu").Cmp(resource.MustParse("2"))).To(Equal(0))
+		Expect(capacity).NotTo(HaveKey(corev1.ResourceLimitsCPU))
+	})
+})
+
+var _ = Describe("ClusterCapacity", func() {
+	It("should complete the explicit capacity with the node allocatable resources", func() {
+		// Given
+		explicit := corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("6"), corev1.ResourceLimitsCPU: resource.MustParse("12")}
+		nodeAllocatable := corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("8"), corev1.ResourcePods: resource.MustParse("110")}
+
+		// When
+		capacity := ClusterCapacity(explicit, nodeAllocatable)
+
+		// Then
+		Expect(quantity(capacity, corev1.ResourceRequestsCPU).Cmp(resource.MustParse("6"))).To(Equal(0))
+		Expect(quantity(capacity, corev1.ResourceLimitsCPU).Cmp(resource.MustParse("12"))).To(Equal(0))
+		Expect(quantity(capacity, corev1.ResourcePods).Cmp(resource.MustParse("110"))).To(Equal(0))
+	})
+})
@@ -0,0 +1,40 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package budget
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Carved is, for every limit, the sum of the same limit of the children
+// projects. A child without that limit carves nothing from it.
+func Carved(limits corev1.ResourceList, children []corev1.ResourceList) corev1.ResourceList {
+	carved := corev1.ResourceList{}
+	for name := range limits {
+		carved[name] = sum(children, name)
+	}
+	return carved
+}
+
+// Plus is, for every limit, the sum of both amounts.
+func Plus(limits corev1.ResourceList, a corev1.ResourceList, b corev1.ResourceList) corev1.ResourceList {
+	total := corev1.ResourceList{}
+	for name := range limits {
+		total[name] = sum([]corev1.ResourceList{a, b}, name)
+	}
+	return total
+}
@@ -0,0 +1,87 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package budget
+
+import (
+	"strings"
+
+	"gopkg.in/inf.v0"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Overcommittable reports whether quotas may allocate more than the project
+// limit of the resource. Only limits.* resources can be overcommitted,
+// requests.* stay strictly capped.
+func Overcommittable(name corev1.ResourceName) bool {
+	return strings.HasPrefix(string(name), "limits.")
+}
+
+// Allocatable returns what the spec.hard of the quotas may add up to for
+// every project limit: the limit times its overcommit factor. Factors of
+// resources that cannot be overcommitted, below 1 or without limit are
+// ignored. The result is rounded down to the milli unit.
+func Allocatable(limits corev1.ResourceList, overcommit corev1.ResourceList) corev1.ResourceList {
+	allocatable := limits.DeepCopy()
+	one := resource.MustParse("1")
+	for name, factor := range overcommit {
+		limit, ok := limits[name]
+		if !ok || !Overcommittable(name) || factor.Cmp(one) <= 0 {
+			continue
+		}
+		product := new(inf.Dec).Mul(limit.AsDec(), factor.AsDec())
+		product.Round(product, 3, inf.RoundDown)
+		allocatable[name] = resource.MustParse(product.String())
+	}
+	return allocatable
+}
+
+// Overcommitted returns the quotas as they count against overcommitted
+// project limits. A short or requests.* cpu, memory or ephemeral-storage
+// amount grants requests, which cannot be overcommitted: against a limits.*
+// limit with a factor above 1 it counts as that many times its amount, so
+// that it fits in the limit itself. Quotas naming the limits.* resource are
+// left as they are.
+func Overcommitted(quotas []corev1.ResourceQuota, overcommit corev1.ResourceList) []corev1.ResourceQuota {
+	one := resource.MustParse("1")
+	counted := make([]corev1.ResourceQuota, 0, len(quotas))
+	for _, quota := range quotas {
+		var hard corev1.ResourceList
+		for limitName := range aliases {
+			factor, ok := overcommit[limitName]
+			if !ok || !Overcommittable(limitName) || factor.Cmp(one) <= 0 {
+				continue
+			}
+			amount, ok := aliasAmount(quota.Spec.Hard, limitName)
+			if _, named := quota.Spec.Hard[limitName]; !ok || named {
+				continue
+			}
+			if hard == nil {
+				hard = quota.Spec.Hard.DeepCopy()
+			}
+			product := new(inf.Dec).Mul(amount.AsDec(), factor.AsDec())
+			product.Round(product, 3, inf.RoundUp)
+			hard[limitName] = resource.MustParse(product.String())
+		}
+		if hard != nil {
+			quota = *quota.DeepCopy()
+			quota.Spec.Hard = hard
+		}
+		counted = append(counted, quota)
+	}
+	return counted
+}
@@ -0,0 +1,145 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	projectv1 "project/api/v1"
+	"project/budget"
+	"project/metrics"
+)
+
+// ClusterBudgetReconciler reconciles a ClusterBudget object
+type ClusterBudgetReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=project.my.domain,resources=clusterbudgets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=project.my.domain,resources=clusterbudgets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+func (r *ClusterBudgetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	logger := r.Log.WithValues("clusterbudget", req.NamespacedName)
+
+	clusterBudget := &projectv1.ClusterBudget{}
+	if err := r.Client.Get(ctx, req.NamespacedName, clusterBudget); err != nil {
+		if errors.IsNotFound(err) {
+			metrics.DeleteClusterBudget(req.Name)
+		}
+		logger.Error(err, "unable to fetch ClusterBudget")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var nodes []corev1.Node
+	if clusterBudget.Spec.NodeAllocatable {
+		nodeList := corev1.NodeList{}
+		if err := r.List(ctx, &nodeList); err != nil {
+			logger.Error(err, "unable to list nodes")
+			return ctrl.Result{}, err
+		}
+		nodes = nodeList.Items
+	}
+
+	projects := projectv1.ProjectList{}
+	if err := r.List(ctx, &projects); err != nil {
+		logger.Error(err, "unable to list projects")
+		return ctrl.Result{}, err
+	}
+
+	status := *clusterBudget.Status.DeepCopy()
+	updateClusterBudgetStatus(clusterBudget, nodes, projects.Items)
+	if !equality.Semantic.DeepEqual(status, clusterBudget.Status) {
+		if err := r.Client.Status().Update(ctx, clusterBudget); err != nil {
+			logger.Error(err, "unable to update ClusterBudget Status")
+			return ctrl.Result{}, err
+		}
+	}
+	metrics.SetClusterBudget(clusterBudget)
+
+	return ctrl.Result{}, nil
+}
+
+// updateClusterBudgetStatus reports the capacity of the cluster budget, what
+// the projects without parent commit of it and whether it is exceeded.
+func updateClusterBudgetStatus(clusterBudget *projectv1.ClusterBudget, nodes []corev1.Node, projects []projectv1.Project) {
+	capacity := budget.ClusterCapacity(clusterBudget.Spec.Capacity, budget.NodeAllocatable(nodes))
+
+	roots := projectv1.RootProjects(projects)
+	limits := make([]corev1.ResourceList, 0, len(roots))
+	for _, project := range roots {
+		limits = append(limits, project.Spec.ProjectLimits)
+	}
+
+	clusterBudget.Status.Capacity = capacity
+	clusterBudget.Status.Committed = budget.Carved(capacity, limits)
+	clusterBudget.Status.Remaining = budget.Remaining(capacity, clusterBudget.Status.Committed)
+	clusterBudget.Status.Projects = len(roots)
+
+	condition := projectv1.ProjectCondition{
+		Type:               projectv1.ClusterBudgetOverCommitted,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: clusterBudget.Generation,
+		Reason:             "CommittedWithinCapacity",
+	}
+	if exceeded := budget.Above(capacity, clusterBudget.Status.Committed); len(exceeded) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "CommittedOverCapacity"
+		condition.Message = "projects commit more than the capacity of the cluster: " + budget.Join(exceeded)
+	}
+	setProjectCondition(&clusterBudget.Status.Conditions, condition)
+}
+
+func (r *ClusterBudgetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	eventHandler := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.clusterBudgetsMapFn)}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&projectv1.ClusterBudget{}).
+		Watches(&source.Kind{Type: &projectv1.Project{}}, eventHandler).
+		Watches(&source.Kind{Type: &corev1.Node{}}, eventHandler).
+		Complete(r)
+}
+
+// clusterBudgetsMapFn enqueues every ClusterBudget, their committed amount
+// or their capacity changes with any project or node.
+func (r *ClusterBudgetReconciler) clusterBudgetsMapFn(object handler.MapObject) []reconcile.Request {
+	clusterBudgets := projectv1.ClusterBudgetList{}
+	if err := r.Client.List(context.Background(), &clusterBudgets); err != nil {
+		r.Log.Error(err, "unable to list ClusterBudgets")
+		return []reconcile.Request{}
+	}
+	requests := make([]reconcile.Request, 0, len(clusterBudgets.Items))
+	for _, clusterBudget := range clusterBudgets.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: clusterBudget.Name}})
+	}
+	return requests
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	projectv1 "project/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("updateClusterBudgetStatus", func() {
+	newProject := func(name string, parent string, cpu string) projectv1.Project {
+		return projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: projectv1.ProjectSpec{
+				Parent:        parent,
+				ProjectLimits: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse(cpu)},
+			},
+		}
+	}
+	node := corev1.Node{Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")}}}
+	projects := []projectv1.Project{newProject("department", "", "6"), newProject("team", "department", "4"), newProject("other", "", "3")}
+
+	It("should report what the projects without parent commit of the node capacity", func() {
+		// Given
+		clusterBudget := projectv1.ClusterBudget{Spec: projectv1.ClusterBudgetSpec{NodeAllocatable: true}}
+
+		// When
+		updateClusterBudgetStatus(&clusterBudget, []corev1.Node{node}, projects)
+
+		// Then
+		committed := clusterBudget.Status.Committed[corev1.ResourceRequestsCPU]
+		remaining := clusterBudget.Status.Remaining[corev1.ResourceRequestsCPU]
+		Expect(clusterBudget.Status.Projects).To(Equal(2))
+		Expect(committed.Value()).To(Equal(int64(9)))
+		Expect(remaining.Value()).To(Equal(int64(-1)))
+		condition := findProjectCondition(clusterBudget.Status.Conditions, projectv1.ClusterBudgetOverCommitted)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(Equal("projects commit more than the capacity of the cluster: cpu, requests.cpu"))
+	})
+
+	It("should not be over-committed within the explicit capacity", func() {
+		// Given
+		clusterBudget := projectv1.ClusterBudget{Spec: projectv1.ClusterBudgetSpec{
+			Capacity: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("16")},
+		}}
+
+		// When
+		updateClusterBudgetStatus(&clusterBudget, nil, projects)
+
+		// Then
+		condition := findProjectCondition(clusterBudget.Status.Conditions, projectv1.ClusterBudgetOverCommitted)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+	})
+})
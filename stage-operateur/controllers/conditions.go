@@ -0,0 +1,80 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	projectv1 "project/api/v1"
+)
+
+// setProjectCondition adds or replaces the condition of the same type. The
+// LastTransitionTime is only moved when the status changes.
+func setProjectCondition(conditions *[]projectv1.ProjectCondition, condition projectv1.ProjectCondition) {
+	for i := range *conditions {
+		existing := &(*conditions)[i]
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		} else if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		*existing = condition
+		return
+	}
+	if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	*conditions = append(*conditions, condition)
+}
+
+// findProjectCondition returns the condition of the given type, or nil.
+func findProjectCondition(conditions []projectv1.ProjectCondition, conditionType string) *projectv1.ProjectCondition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// setNamespaceCondition adds or replaces the condition of the same type on the
+// namespace status and reports whether anything changed.
+func setNamespaceCondition(namespace *corev1.Namespace, condition corev1.NamespaceCondition) bool {
+	for i := range namespace.Status.Conditions {
+		existing := &namespace.Status.Conditions[i]
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status && existing.Reason == condition.Reason && existing.Message == condition.Message {
+			return false
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		} else {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		*existing = condition
+		return true
+	}
+	condition.LastTransitionTime = metav1.Now()
+	namespace.Status.Conditions = append(namespace.Status.Conditions, condition)
+	return true
+}
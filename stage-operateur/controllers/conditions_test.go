@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	projectv1 "project/api/v1"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("setProjectCondition", func() {
+	It("should add a condition with a transition time", func() {
+		// Given
+		var conditions []projectv1.ProjectCondition
+
+		// When
+		setProjectCondition(&conditions, projectv1.ProjectCondition{Type: projectv1.ProjectReady, Status: metav1.ConditionTrue, Reason: "DefaultQuotasPresent"})
+
+		// Then
+		Expect(conditions).To(HaveLen(1))
+		Expect(conditions[0].LastTransitionTime.IsZero()).To(BeFalse())
+	})
+
+	It("should keep the transition time when the status does not change", func() {
+		// Given
+		transition := metav1.NewTime(metav1.Now().Add(-time.Hour))
+		conditions := []projectv1.ProjectCondition{{Type: projectv1.ProjectReady, Status: metav1.ConditionTrue, Reason: "Old", LastTransitionTime: transition}}
+
+		// When
+		setProjectCondition(&conditions, projectv1.ProjectCondition{Type: projectv1.ProjectReady, Status: metav1.ConditionTrue, Reason: "DefaultQuotasPresent"})
+
+		// Then
+		Expect(conditions).To(HaveLen(1))
+		Expect(conditions[0].Reason).To(Equal("DefaultQuotasPresent"))
+		Expect(conditions[0].LastTransitionTime).To(Equal(transition))
+	})
+
+	It("should move the transition time when the status changes", func() {
+		// Given
+		transition := metav1.NewTime(metav1.Now().Add(-time.Hour))
+		conditions := []projectv1.ProjectCondition{{Type: projectv1.ProjectReady, Status: metav1.ConditionTrue, LastTransitionTime: transition}}
+
+		// When
+		setProjectCondition(&conditions, projectv1.ProjectCondition{Type: projectv1.ProjectReady, Status: metav1.ConditionFalse, Reason: "DefaultQuotaMissing"})
+
+		// Then
+		Expect(conditions[0].Status).To(Equal(metav1.ConditionFalse))
+		Expect(conditions[0].LastTransitionTime.After(transition.Time)).To(BeTrue())
+	})
+})
+
+var _ = Describe("setNamespaceCondition", func() {
+	It("should report no change when the condition is identical", func() {
+		// Given
+		namespace := corev1.Namespace{}
+		condition := projectNotFoundCondition("project-1", false)
+		setNamespaceCondition(&namespace, condition)
+
+		// When
+		changed := setNamespaceCondition(&namespace, condition)
+
+		// Then
+		Expect(changed).To(BeFalse())
+		Expect(namespace.Status.Conditions).To(HaveLen(1))
+	})
+
+	It("should replace a condition of the same type", func() {
+		// Given
+		namespace := corev1.Namespace{}
+		setNamespaceCondition(&namespace, projectNotFoundCondition("project-1", false))
+
+		// When
+		changed := setNamespaceCondition(&namespace, projectNotFoundCondition("project-1", true))
+
+		// Then
+		Expect(changed).To(BeTrue())
+		Expect(namespace.Status.Conditions).To(HaveLen(1))
+		Expect(namespace.Status.Conditions[0].Status).To(Equal(corev1.ConditionFalse))
+	})
+})
@@ -0,0 +1,44 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	projectv1 "project/api/v1"
+	"project/budget"
+)
+
+// updateProjectHierarchy reports the limits carved by the children of the
+// project and how the quotas of its whole subtree roll up to it. Remaining
+// is reduced by what the children carve.
+func updateProjectHierarchy(children []projectv1.Project, subtreeQuotas []corev1.ResourceQuota, project *projectv1.Project) {
+	limits := project.Spec.ProjectLimits
+
+	names := make([]string, 0, len(children))
+	childLimits := make([]corev1.ResourceList, 0, len(children))
+	for _, child := range children {
+		names = append(names, child.Name)
+		childLimits = append(childLimits, child.Spec.ProjectLimits)
+	}
+	project.Status.Children = names
+
+	project.Status.Carved = budget.Carved(limits, childLimits)
+	project.Status.SubtreeAllocated = budget.Allocated(limits, subtreeQuotas)
+	project.Status.SubtreeUsed = budget.Used(limits, subtreeQuotas)
+	project.Status.Remaining = budget.Remaining(budget.Allocatable(limits, project.Spec.Overcommit), budget.Plus(limits, project.Status.Allocated, project.Status.Carved))
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	projectv1 "project/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("updateProjectHierarchy", func() {
+	newProject := func(name string, parent string, cpu string) projectv1.Project {
+		return projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: projectv1.ProjectSpec{
+				Parent:        parent,
+				ProjectLimits: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse(cpu)},
+			},
+		}
+	}
+	newQuota := func(namespace string, hardCpu string, usedCpu string) corev1.ResourceQuota {
+		return corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "project-quota", Namespace: namespace},
+			Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(hardCpu)}},
+			Status:     corev1.ResourceQuotaStatus{Used: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(usedCpu)}},
+		}
+	}
+
+	It("should roll the budget of the children up to the project", func() {
+		// Given
+		project := newProject("department", "", "10")
+		children := []projectv1.Project{newProject("team-a", "department", "4"), newProject("team-b", "department", "2")}
+		quotas := []corev1.ResourceQuota{newQuota("department-tools", "1", "500m")}
+		subtreeQuotas := append(quotas, newQuota("team-a-app", "3", "1"), newQuota("service-x", "1", "1"))
+		updateProjectUsage(quotas, &project)
+
+		// When
+		updateProjectHierarchy(children, subtreeQuotas, &project)
+
+		// Then
+		carved := project.Status.Carved[corev1.ResourceLimitsCPU]
+		subtreeAllocated := project.Status.SubtreeAllocated[corev1.ResourceLimitsCPU]
+		subtreeUsed := project.Status.SubtreeUsed[corev1.ResourceLimitsCPU]
+		remaining := project.Status.Remaining[corev1.ResourceLimitsCPU]
+		Expect(project.Status.Children).To(Equal([]string{"team-a", "team-b"}))
+		Expect(carved.Value()).To(Equal(int64(6)))
+		Expect(subtreeAllocated.Value()).To(Equal(int64(5)))
+		Expect(subtreeUsed.MilliValue()).To(Equal(int64(2500)))
+		Expect(remaining.Value()).To(Equal(int64(3)))
+	})
+
+	It("should not follow cycles when looking for descendants", func() {
+		// Given
+		projects := []projectv1.Project{newProject("a", "b", "1"), newProject("b", "a", "1"), newProject("c", "a", "1")}
+
+		// Then
+		Expect(projectv1.DescendantProjects(projects, "a")).To(ConsistOf("b", "c"))
+	})
+})
@@ -0,0 +1,139 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	projectv1 "project/api/v1"
+)
+
+var projectRoles = []projectv1.ProjectRole{projectv1.ProjectRoleAdmin, projectv1.ProjectRoleEdit, projectv1.ProjectRoleView}
+
+// memberRoleBindings returns the RoleBindings granting the project members
+// their role in the namespace, one per role that has members.
+func memberRoleBindings(project *projectv1.Project, namespace string) []rbacv1.RoleBinding {
+	var roleBindings []rbacv1.RoleBinding
+	for _, role := range projectRoles {
+		var subjects []rbacv1.Subject
+		for _, member := range project.Spec.Members {
+			if member.Role != role {
+				continue
+			}
+			subject := rbacv1.Subject{Kind: member.Kind, Name: member.Name}
+			switch member.Kind {
+			case rbacv1.ServiceAccountKind:
+				subject.Namespace = member.Namespace
+				if subject.Namespace == "" {
+					subject.Namespace = namespace
+				}
+			default:
+				subject.APIGroup = rbacv1.GroupName
+			}
+			subjects = append(subjects, subject)
+		}
+		if len(subjects) == 0 {
+			continue
+		}
+
+		roleBindings = append(roleBindings, rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "project-" + string(role),
+				Namespace: namespace,
+				Labels: map[string]string{
+					projectv1.ProjectMembersLabel: project.Name,
+				},
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     string(role),
+			},
+			Subjects: subjects,
+		})
+	}
+	return roleBindings
+}
+
+// syncMembers keeps the member RoleBindings of the namespace in sync with the
+// project members, and deletes the ones of roles left without members.
+func (r *NamespaceReconciler) syncMembers(ctx context.Context, project *projectv1.Project, namespace string) error {
+	wanted := map[string]bool{}
+	for _, roleBinding := range memberRoleBindings(project, namespace) {
+		roleBinding := roleBinding
+		wanted[roleBinding.Name] = true
+
+		existing := rbacv1.RoleBinding{}
+		err := r.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: roleBinding.Name}, &existing)
+		if errors.IsNotFound(err) {
+			if err := r.Client.Create(ctx, &roleBinding); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// The roleRef of a RoleBinding cannot be changed
+		if existing.RoleRef != roleBinding.RoleRef {
+			if err := r.Client.Delete(ctx, &existing); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			if err := r.Client.Create(ctx, &roleBinding); err != nil {
+				return err
+			}
+			continue
+		}
+		if !reflect.DeepEqual(existing.Subjects, roleBinding.Subjects) || existing.Labels[projectv1.ProjectMembersLabel] != project.Name {
+			existing.Subjects = roleBinding.Subjects
+			if existing.Labels == nil {
+				existing.Labels = map[string]string{}
+			}
+			existing.Labels[projectv1.ProjectMembersLabel] = project.Name
+			if err := r.Client.Update(ctx, &existing); err != nil {
+				return err
+			}
+		}
+	}
+
+	return r.deleteMemberRoleBindings(ctx, namespace, wanted)
+}
+
+// deleteMemberRoleBindings deletes the member RoleBindings of the namespace
+// that are not kept.
+func (r *NamespaceReconciler) deleteMemberRoleBindings(ctx context.Context, namespace string, keep map[string]bool) error {
+	roleBindings := rbacv1.RoleBindingList{}
+	if err := r.Client.List(ctx, &roleBindings, client.InNamespace(namespace), client.HasLabels{projectv1.ProjectMembersLabel}); err != nil {
+		return err
+	}
+	for i := range roleBindings.Items {
+		if keep[roleBindings.Items[i].Name] {
+			continue
+		}
+		if err := r.Client.Delete(ctx, &roleBindings.Items[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
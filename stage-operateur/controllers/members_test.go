@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	projectv1 "project/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("memberRoleBindings", func() {
+	It("should bind the members of every role in the namespace", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{Name: "project-test1"},
+			Spec: projectv1.ProjectSpec{
+				Members: []projectv1.ProjectMember{
+					{Kind: "User", Name: "alice", Role: projectv1.ProjectRoleView},
+					{Kind: "Group", Name: "team", Role: projectv1.ProjectRoleAdmin},
+					{Kind: "ServiceAccount", Name: "deployer", Role: projectv1.ProjectRoleView},
+				},
+			},
+		}
+
+		// When
+		roleBindings := memberRoleBindings(&project, "test1")
+
+		// Then
+		Expect(roleBindings).To(HaveLen(2))
+		Expect(roleBindings[0].Name).To(Equal("project-admin"))
+		Expect(roleBindings[0].RoleRef.Name).To(Equal("admin"))
+		Expect(roleBindings[0].Labels).To(HaveKeyWithValue(projectv1.ProjectMembersLabel, "project-test1"))
+		Expect(roleBindings[1].Name).To(Equal("project-view"))
+		Expect(roleBindings[1].Subjects).To(Equal([]rbacv1.Subject{
+			{Kind: "User", APIGroup: rbacv1.GroupName, Name: "alice"},
+			{Kind: "ServiceAccount", Name: "deployer", Namespace: "test1"},
+		}))
+	})
+
+	It("should not bind anything without members", func() {
+		// When
+		roleBindings := memberRoleBindings(&projectv1.Project{}, "test1")
+
+		// Then
+		Expect(roleBindings).To(BeEmpty())
+	})
+})
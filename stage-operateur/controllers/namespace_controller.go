@@ -19,7 +19,6 @@ package controllers
 import (
 	"context"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -28,9 +27,16 @@ import (
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	projectv1 "project/api/v1"
+	"project/budget"
+	"project/index"
 )
 
 // +kubebuilder:rbac:groups=core,resources=namespaces;resourcequotas,verbs=get;list;watch;create;update;patch;delete
@@ -41,6 +47,13 @@ type NamespaceReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// Recorder, when set, emits an Event on the project when the operator
+	// creates or updates the project-quota of one of its namespaces
+	Recorder record.EventRecorder
+	// AccountingMode selects the ResourceQuotas counted against the project limits
+	AccountingMode budget.AccountingMode
+	// Names are the project label and the project-quota name
+	Names projectv1.Names
 }
 
 func (r *NamespaceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -53,75 +66,299 @@ func (r *NamespaceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	if resourceQuotaShouldBePresent(&namespace) {
+	if resourceQuotaShouldBePresent(r.Names, &namespace) {
 		logger.Info("label 'project' was set")
 	} else {
+		// A namespace that left its project no longer grants access to the
+		// project members, holds its template objects nor is isolated with
+		// the project
+		if namespace.DeletionTimestamp.IsZero() {
+			if err := r.deleteMemberRoleBindings(ctx, namespace.Name, nil); err != nil {
+				logger.Error(err, "unable to revoke the project members")
+				return ctrl.Result{}, err
+			}
+			if err := r.deleteTemplateObjects(ctx, namespace.Name, "", nil); err != nil {
+				logger.Error(err, "unable to remove the namespace template")
+				return ctrl.Result{}, err
+			}
+			if err := r.deleteIsolationPolicy(ctx, namespace.Name); err != nil {
+				logger.Error(err, "unable to remove the network isolation")
+				return ctrl.Result{}, err
+			}
+		}
 		logger.Info("label 'project' is not set, ending reconciliation")
 		return ctrl.Result{}, nil
 	}
 
-	// Create resourceQuota "project-quota" and ignore err existAlready
-	quotaDefault := newDefaultResourceQuota(req.Name, namespace.ObjectMeta.Labels["project"])
+	projectName := namespace.ObjectMeta.Labels[r.Names.Label()]
+	project := projectv1.Project{}
+	projectFound := true
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: projectName}, &project); err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "unable to get project")
+			return ctrl.Result{}, err
+		}
+		logger.Info("label 'project' points at a missing Project", "project", projectName)
+		projectFound = false
+	}
+
+	if hasNamespaceCondition(&namespace, projectv1.NamespaceProjectNotFound) || !projectFound {
+		if setNamespaceCondition(&namespace, projectNotFoundCondition(projectName, projectFound)) {
+			if err := r.Client.Status().Update(ctx, &namespace); err != nil {
+				logger.Error(err, "unable to update Namespace Status")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	// The project-quota of a deleted project is removed, not recreated
+	if projectFound && !project.DeletionTimestamp.IsZero() {
+		logger.Info("project is being deleted, ending reconciliation", "project", projectName)
+		return ctrl.Result{}, nil
+	}
+
+	if !projectFound {
+		// Create resourceQuota "project-quota" and ignore err existAlready
+		quotaDefault := newDefaultResourceQuota(r.Names, req.Name, projectName)
 
-	if err := r.Client.Create(ctx, &quotaDefault); err != nil {
-		if errors.IsAlreadyExists(err) {
-			return ctrl.Result{}, nil
+		if err := r.Client.Create(ctx, &quotaDefault); err != nil && !errors.IsAlreadyExists(err) {
+			logger.Error(err, "unable to get project")
+			return ctrl.Result{}, err
+		}
+		// The template objects of the project the namespace left are removed
+		if err := r.deleteTemplateObjects(ctx, namespace.Name, projectName, nil); err != nil {
+			logger.Error(err, "unable to remove the namespace template")
+			return ctrl.Result{}, err
 		}
-		logger.Error(err, "unable to get project")
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.syncProjectQuota(ctx, &namespace, &project); err != nil {
+		logger.Error(err, "unable to sync the project-quota", "project", projectName)
+		return ctrl.Result{}, err
+	}
+	// A template that cannot be stamped is reported on the namespace, it
+	// does not keep the members and the network isolation from being synced.
+	templateErr := r.syncTemplate(ctx, &project, namespace.Name)
+	if templateErr != nil {
+		logger.Error(templateErr, "unable to sync the namespace template", "project", projectName)
+	}
+	if templateErr != nil || hasNamespaceCondition(&namespace, projectv1.NamespaceTemplateFailed) {
+		if setNamespaceCondition(&namespace, templateFailedCondition(projectName, templateErr)) {
+			if err := r.Client.Status().Update(ctx, &namespace); err != nil {
+				logger.Error(err, "unable to update Namespace Status")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	if err := r.syncMembers(ctx, &project, namespace.Name); err != nil {
+		logger.Error(err, "unable to sync the project members", "project", projectName)
+		return ctrl.Result{}, err
+	}
+	if err := r.syncNetworkIsolation(ctx, &project, namespace.Name); err != nil {
+		logger.Error(err, "unable to sync the network isolation", "project", projectName)
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{}, templateErr
 }
 
-func newDefaultResourceQuota(namespaceName string, projectName string) corev1.ResourceQuota {
+func newDefaultResourceQuota(names projectv1.Names, namespaceName string, projectName string) corev1.ResourceQuota {
 
 	quotaDefault := corev1.ResourceQuota{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "project-quota",
+			Name:      names.QuotaName(),
 			Namespace: namespaceName,
 			Labels: map[string]string{
-				"project": projectName,
-			},
-		},
-		Spec: corev1.ResourceQuotaSpec{
-			Hard: corev1.ResourceList{
-				corev1.ResourceCPU:    *resource.NewQuantity(0, resource.DecimalSI),
-				corev1.ResourceMemory: *resource.NewQuantity(0, resource.BinarySI),
+				names.Label():                      projectName,
+				projectv1.ProjectQuotaManagedLabel: "true",
 			},
 		},
+		Spec: projectv1.DefaultQuotaSpec(),
 	}
 
 	return quotaDefault
 }
 
-func resourceQuotaShouldBePresent(namespace *corev1.Namespace) bool {
+// createProjectQuota creates the project-quota of a new namespace from the
+// project's defaultNamespaceQuota. When it does not fit in the remaining
+// budget of the project, the project-quota has no cpu and no memory and the
+// namespace condition DefaultQuotaReduced tells why.
+func (r *NamespaceReconciler) createProjectQuota(ctx context.Context, namespace *corev1.Namespace, project *projectv1.Project, quotas []corev1.ResourceQuota) error {
+	quota := newProjectResourceQuota(r.Names, namespace.Name, project)
+	exceeded := budget.Overrun(budget.Allocatable(project.Spec.ProjectLimits, project.Spec.Overcommit), budget.Overcommitted(append(quotas, quota), project.Spec.Overcommit), corev1.ResourceList{}, quota.Spec.Hard)
+	if len(exceeded) > 0 {
+		quota = newDefaultResourceQuota(r.Names, namespace.Name, project.Name)
+	}
+	setDefaultQuotaHash(&quota)
+
+	err := r.Client.Create(ctx, &quota)
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	if err == nil {
+		r.recordProjectQuotaCreated(project, namespace.Name, exceeded)
+	}
+
+	if len(exceeded) > 0 || hasNamespaceCondition(namespace, projectv1.NamespaceDefaultQuotaReduced) {
+		if setNamespaceCondition(namespace, defaultQuotaReducedCondition(project.Name, exceeded)) {
+			return r.Client.Status().Update(ctx, namespace)
+		}
+	}
+	return nil
+}
+
+// recordProjectQuotaCreated tells the owners of the project which quota the
+// new namespace got.
+func (r *NamespaceReconciler) recordProjectQuotaCreated(project *projectv1.Project, namespaceName string, exceeded []corev1.ResourceName) {
+	if r.Recorder == nil {
+		return
+	}
+	if len(exceeded) > 0 {
+		r.Recorder.Event(project, corev1.EventTypeWarning, "DefaultQuotaReduced",
+			"project-quota of namespace "+namespaceName+" created with no cpu and no memory, the defaultNamespaceQuota exceeds the remaining budget: "+budget.Join(exceeded))
+		return
+	}
+	r.Recorder.Event(project, corev1.EventTypeNormal, "DefaultQuotaCreated",
+		"project-quota of namespace "+namespaceName+" created from the defaultNamespaceQuota")
+}
+
+// newProjectResourceQuota is the project-quota of a new namespace of the project
+func newProjectResourceQuota(names projectv1.Names, namespaceName string, project *projectv1.Project) corev1.ResourceQuota {
+	quota := newDefaultResourceQuota(names, namespaceName, project.Name)
+	quota.Spec = project.NamespaceQuotaSpec()
+	return quota
+}
+
+func defaultQuotaReducedCondition(projectName string, exceeded []corev1.ResourceName) corev1.NamespaceCondition {
+	if len(exceeded) == 0 {
+		return corev1.NamespaceCondition{
+			Type:    projectv1.NamespaceDefaultQuotaReduced,
+			Status:  corev1.ConditionFalse,
+			Reason:  "DefaultQuotaApplied",
+			Message: "project-quota created from the defaultNamespaceQuota of Project " + projectName,
+		}
+	}
+	return corev1.NamespaceCondition{
+		Type:    projectv1.NamespaceDefaultQuotaReduced,
+		Status:  corev1.ConditionTrue,
+		Reason:  "ProjectBudgetShort",
+		Message: "the defaultNamespaceQuota of Project " + projectName + " exceeds its remaining budget: " + budget.Join(exceeded) + "; project-quota created with no cpu and no memory",
+	}
+}
+
+func projectNotFoundCondition(projectName string, projectFound bool) corev1.NamespaceCondition {
+	if projectFound {
+		return corev1.NamespaceCondition{
+			Type:    projectv1.NamespaceProjectNotFound,
+			Status:  corev1.ConditionFalse,
+			Reason:  "ProjectFound",
+			Message: "label 'project' points at Project " + projectName,
+		}
+	}
+	return corev1.NamespaceCondition{
+		Type:    projectv1.NamespaceProjectNotFound,
+		Status:  corev1.ConditionTrue,
+		Reason:  "ProjectMissing",
+		Message: "label 'project' points at Project " + projectName + " which does not exist",
+	}
+}
+
+func templateFailedCondition(projectName string, err error) corev1.NamespaceCondition {
+	if err == nil {
+		return corev1.NamespaceCondition{
+			Type:    projectv1.NamespaceTemplateFailed,
+			Status:  corev1.ConditionFalse,
+			Reason:  "TemplateApplied",
+			Message: "namespace template of Project " + projectName + " applied",
+		}
+	}
+	return corev1.NamespaceCondition{
+		Type:    projectv1.NamespaceTemplateFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  "TemplateNotApplied",
+		Message: "namespace template of Project " + projectName + " could not be applied: " + err.Error(),
+	}
+}
+
+func hasNamespaceCondition(namespace *corev1.Namespace, conditionType corev1.NamespaceConditionType) bool {
+	for _, condition := range namespace.Status.Conditions {
+		if condition.Type == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+func resourceQuotaShouldBePresent(names projectv1.Names, namespace *corev1.Namespace) bool {
 	//	logger.Info("namespace is terminating, ending reconciliation")
 	//		return ctrl.Result{}, nil
 
-	_, ok := namespace.Labels["project"]
+	_, ok := namespace.Labels[names.Label()]
 
 	return namespace.DeletionTimestamp.IsZero() && ok
 }
 
 func (r *NamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	eventHandler := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.namespaceMapFn)}
+	projectEventHandler := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.projectMapFn)}
+	managedEventHandler := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(managedObjectMapFn)}
+	quotaEventHandler := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.projectQuotaMapFn)}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Namespace{}).
-		Watches(&source.Kind{Type: &corev1.Namespace{}}, eventHandler).Named("Namespace").
+		Watches(&source.Kind{Type: &projectv1.Project{}}, projectEventHandler).
+		Watches(&source.Kind{Type: &corev1.ResourceQuota{}}, quotaEventHandler).
+		Watches(&source.Kind{Type: &corev1.LimitRange{}}, managedEventHandler).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, managedEventHandler).
+		Watches(&source.Kind{Type: &networkingv1.NetworkPolicy{}}, managedEventHandler).
+		Watches(&source.Kind{Type: &rbacv1.RoleBinding{}}, managedEventHandler).Named("Namespace").
 		Complete(r)
 }
 
-func (r *NamespaceReconciler) namespaceMapFn(handler.MapObject) []reconcile.Request {
+// managedLabels mark the objects the operator maintains in project namespaces
+var managedLabels = []string{
+	projectv1.NamespaceTemplateLabel,
+	projectv1.ProjectMembersLabel,
+	projectv1.NetworkIsolationLabel,
+}
+
+// managedObjectMapFn enqueues the namespace of an object maintained by the
+// operator, so that drift is corrected.
+func managedObjectMapFn(object handler.MapObject) []reconcile.Request {
+	labels := object.Meta.GetLabels()
+	managed := false
+	for _, label := range managedLabels {
+		if _, ok := labels[label]; ok {
+			managed = true
+		}
+	}
+	if !managed {
+		return []reconcile.Request{}
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: object.Meta.GetNamespace()}}}
+}
+
+// projectQuotaMapFn enqueues the namespace of a project-quota, labelled or
+// not, so that drift is corrected.
+func (r *NamespaceReconciler) projectQuotaMapFn(object handler.MapObject) []reconcile.Request {
+	if object.Meta.GetName() != r.Names.QuotaName() {
+		return []reconcile.Request{}
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: object.Meta.GetNamespace()}}}
+}
+
+// projectMapFn enqueues the namespaces labelled with a project when the
+// project is created, updated or deleted. They are found through the field
+// index index.NamespaceProject.
+func (r *NamespaceReconciler) projectMapFn(object handler.MapObject) []reconcile.Request {
 	ctx := context.Background()
 
 	NamespaceList := &corev1.NamespaceList{}
-	if err := r.List(ctx, NamespaceList); err != nil {
+	if err := r.List(ctx, NamespaceList, client.MatchingFields{index.NamespaceProject: object.Meta.GetName()}); err != nil {
 		return []reconcile.Request{}
 	}
 	requests := make([]reconcile.Request, 0, len(NamespaceList.Items))
 	for _, namespace := range NamespaceList.Items {
-		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace.GetNamespace(), Name: namespace.GetName()}})
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: namespace.GetName()}})
 	}
 	return requests
 }
@@ -1,9 +1,13 @@
 package controllers
 
 import (
+	"errors"
+	projectv1 "project/api/v1"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -13,7 +17,7 @@ var _ = Describe("resourceQuotaShouldBePresent", func() {
 		// Given
 		namespace := corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "test1", Labels: map[string]string{"project": "patate"}}}
 		// When
-		result := resourceQuotaShouldBePresent(&namespace)
+		result := resourceQuotaShouldBePresent(projectv1.Names{}, &namespace)
 
 		// Then
 		Expect(result).To(BeTrue())
@@ -25,7 +29,7 @@ var _ = Describe("resourceQuotaShouldBePresent", func() {
 
 		namespace := corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "test1", DeletionTimestamp: &instant, Labels: map[string]string{"project": "patate"}}}
 		// When
-		result := resourceQuotaShouldBePresent(&namespace)
+		result := resourceQuotaShouldBePresent(projectv1.Names{}, &namespace)
 
 		// Then
 		Expect(result).To(BeFalse())
@@ -35,7 +39,7 @@ var _ = Describe("resourceQuotaShouldBePresent", func() {
 		// Given
 		namespace := corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "test1", Labels: map[string]string{"patate": "saute"}}}
 		// When
-		result := resourceQuotaShouldBePresent(&namespace)
+		result := resourceQuotaShouldBePresent(projectv1.Names{}, &namespace)
 
 		// Then
 		Expect(result).To(BeFalse())
@@ -52,7 +56,7 @@ var _ = Describe("newDefaultResourceQuota", func() {
 		var expectedMemory int64 = 0
 		var expectedCpu int64 = 0
 		// When
-		quota := newDefaultResourceQuota(namespaceName, projectName)
+		quota := newDefaultResourceQuota(projectv1.Names{}, namespaceName, projectName)
 
 		// Then
 		Expect(quota.ObjectMeta.Namespace).To(Equal(namespaceName))
@@ -62,4 +66,101 @@ var _ = Describe("newDefaultResourceQuota", func() {
 		Expect(quota.ObjectMeta.Name).To(Equal("project-quota"))
 	})
 
+	It("should use the configured project label and project-quota name", func() {
+		// Given
+		names := projectv1.Names{ProjectLabel: "team", ProjectQuotaName: "team-quota"}
+
+		// When
+		quota := newDefaultResourceQuota(names, "namespace-1", "project-1")
+
+		// Then
+		Expect(quota.ObjectMeta.Name).To(Equal("team-quota"))
+		Expect(quota.ObjectMeta.Labels).To(HaveKeyWithValue("team", "project-1"))
+		Expect(quota.ObjectMeta.Labels).NotTo(HaveKey("project"))
+	})
+
+})
+
+var _ = Describe("newProjectResourceQuota", func() {
+	It("should use the defaultNamespaceQuota of the project, scopes included", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: v1.ObjectMeta{Name: "project-1"},
+			Spec: projectv1.ProjectSpec{
+				DefaultNamespaceQuota: &corev1.ResourceQuotaSpec{
+					Hard:   corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1")},
+					Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotBestEffort},
+				},
+			},
+		}
+
+		// When
+		quota := newProjectResourceQuota(projectv1.Names{}, "namespace-1", &project)
+
+		// Then
+		Expect(quota.Name).To(Equal("project-quota"))
+		Expect(quota.Labels["project"]).To(Equal("project-1"))
+		Expect(quota.Spec).To(Equal(*project.Spec.DefaultNamespaceQuota))
+	})
+
+	It("should default to no cpu and no memory", func() {
+		// When
+		quota := newProjectResourceQuota(projectv1.Names{}, "namespace-1", &projectv1.Project{})
+
+		// Then
+		Expect(quota.Spec).To(Equal(projectv1.DefaultQuotaSpec()))
+	})
+})
+
+var _ = Describe("defaultQuotaReducedCondition", func() {
+	It("should explain which limits the default quota exceeds", func() {
+		// When
+		condition := defaultQuotaReducedCondition("project-1", []corev1.ResourceName{corev1.ResourceLimitsCPU})
+
+		// Then
+		Expect(condition.Type).To(Equal(projectv1.NamespaceDefaultQuotaReduced))
+		Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+		Expect(condition.Reason).To(Equal("ProjectBudgetShort"))
+		Expect(condition.Message).To(ContainSubstring("limits.cpu"))
+	})
+})
+
+var _ = Describe("projectNotFoundCondition", func() {
+	It("should be true when the project is missing", func() {
+		// When
+		condition := projectNotFoundCondition("project-1", false)
+
+		// Then
+		Expect(condition.Type).To(Equal(projectv1.NamespaceProjectNotFound))
+		Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+		Expect(condition.Message).To(ContainSubstring("project-1"))
+	})
+
+	It("should be false when the project exists", func() {
+		// When
+		condition := projectNotFoundCondition("project-1", true)
+
+		// Then
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+	})
+})
+
+var _ = Describe("templateFailedCondition", func() {
+	It("should be true with the reason the template could not be applied", func() {
+		// When
+		condition := templateFailedCondition("project-1", errors.New("namespaceTemplate object 0 has no name"))
+
+		// Then
+		Expect(condition.Type).To(Equal(projectv1.NamespaceTemplateFailed))
+		Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+		Expect(condition.Message).To(ContainSubstring("namespaceTemplate object 0 has no name"))
+	})
+
+	It("should be false once the template is applied", func() {
+		// When
+		condition := templateFailedCondition("project-1", nil)
+
+		// Then
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+	})
 })
@@ -0,0 +1,214 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	projectv1 "project/api/v1"
+	"project/budget"
+	"project/index"
+)
+
+// NamespaceRequestReconciler reconciles a NamespaceRequest object
+type NamespaceRequestReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// AccountingMode selects the ResourceQuotas counted against the project limits
+	AccountingMode budget.AccountingMode
+	// Names are the project label and the project-quota name
+	Names projectv1.Names
+}
+
+// +kubebuilder:rbac:groups=project.my.domain,resources=namespacerequests,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=project.my.domain,resources=namespacerequests/status,verbs=get;update;patch
+
+func (r *NamespaceRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	logger := r.Log.WithValues("namespacerequest", req.NamespacedName)
+
+	request := &projectv1.NamespaceRequest{}
+	if err := r.Client.Get(ctx, req.NamespacedName, request); err != nil {
+		logger.Error(err, "unable to fetch NamespaceRequest")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if request.Status.Phase == projectv1.NamespaceRequestCreated {
+		return ctrl.Result{}, nil
+	}
+	if request.Status.Phase == projectv1.NamespaceRequestDenied && request.Status.ObservedGeneration == request.Generation {
+		return ctrl.Result{}, nil
+	}
+
+	status, err := r.grantNamespaceRequest(ctx, request)
+	if err != nil {
+		logger.Error(err, "unable to create the requested namespace", "namespace", request.Spec.Namespace)
+		return ctrl.Result{}, err
+	}
+	logger.Info("namespace request handled", "phase", status.Phase, "message", status.Message)
+
+	request.Status = status
+	request.Status.ObservedGeneration = request.Generation
+	if err := r.Client.Status().Update(ctx, request); err != nil {
+		logger.Error(err, "unable to update NamespaceRequest Status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// grantNamespaceRequest creates the requested namespace and its project-quota
+// when the quota fits in the limits of the project of the request.
+func (r *NamespaceRequestReconciler) grantNamespaceRequest(ctx context.Context, request *projectv1.NamespaceRequest) (projectv1.NamespaceRequestStatus, error) {
+	requestNamespace := corev1.Namespace{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: request.Namespace}, &requestNamespace); err != nil {
+		return projectv1.NamespaceRequestStatus{}, err
+	}
+	projectName, ok := requestNamespace.Labels[r.Names.Label()]
+	if !ok {
+		return deniedNamespaceRequest("", "namespace "+request.Namespace+" does not belong to a project"), nil
+	}
+
+	project := projectv1.Project{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: projectName}, &project)
+	if errors.IsNotFound(err) {
+		return deniedNamespaceRequest(projectName, "project "+projectName+" does not exist"), nil
+	}
+	if err != nil {
+		return projectv1.NamespaceRequestStatus{}, err
+	}
+	if !project.DeletionTimestamp.IsZero() {
+		return deniedNamespaceRequest(projectName, "project "+projectName+" is being deleted"), nil
+	}
+
+	requestKey := request.Namespace + "/" + request.Name
+	namespace := corev1.Namespace{}
+	err = r.Client.Get(ctx, client.ObjectKey{Name: request.Spec.Namespace}, &namespace)
+	if err != nil && !errors.IsNotFound(err) {
+		return projectv1.NamespaceRequestStatus{}, err
+	}
+	if err == nil && namespace.Annotations[projectv1.NamespaceRequestAnnotation] != requestKey {
+		return deniedNamespaceRequest(projectName, "namespace "+request.Spec.Namespace+" already exists"), nil
+	}
+
+	if errors.IsNotFound(err) {
+		namespaces := corev1.NamespaceList{}
+		if err := r.Client.List(ctx, &namespaces, client.MatchingFields{index.NamespaceProject: projectName}); err != nil {
+			return projectv1.NamespaceRequestStatus{}, err
+		}
+		names := make([]string, 0, len(namespaces.Items))
+		for _, namespace := range namespaces.Items {
+			names = append(names, namespace.Name)
+		}
+		quotas, err := projectResourceQuotas(ctx, r.Client, r.AccountingMode, r.Names, names)
+		if err != nil {
+			return projectv1.NamespaceRequestStatus{}, err
+		}
+		if exceeded := namespaceRequestOverrun(r.Names, &project, quotas, request); len(exceeded) > 0 {
+			return deniedNamespaceRequest(projectName, "the quota exceeds the limits of project "+projectName+": "+budget.Join(exceeded)), nil
+		}
+
+		namespace = corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        request.Spec.Namespace,
+				Labels:      map[string]string{r.Names.Label(): projectName},
+				Annotations: map[string]string{projectv1.NamespaceRequestAnnotation: requestKey},
+			},
+		}
+		if err := r.Client.Create(ctx, &namespace); err != nil {
+			if errors.IsForbidden(err) || errors.IsInvalid(err) {
+				return deniedNamespaceRequest(projectName, err.Error()), nil
+			}
+			return projectv1.NamespaceRequestStatus{}, err
+		}
+	}
+
+	if err := r.sizeProjectQuota(ctx, &project, request); err != nil {
+		if errors.IsForbidden(err) || errors.IsInvalid(err) {
+			// The webhook also checks the quota against the ancestors of the
+			// project: the namespace is not left in the project without it.
+			if err := r.Client.Delete(ctx, &namespace); client.IgnoreNotFound(err) != nil {
+				return projectv1.NamespaceRequestStatus{}, err
+			}
+			return deniedNamespaceRequest(projectName, "the project-quota was refused, namespace "+request.Spec.Namespace+" deleted: "+err.Error()), nil
+		}
+		return projectv1.NamespaceRequestStatus{}, err
+	}
+
+	return projectv1.NamespaceRequestStatus{
+		Phase:   projectv1.NamespaceRequestCreated,
+		Project: projectName,
+		Message: "namespace " + request.Spec.Namespace + " created in project " + projectName,
+	}, nil
+}
+
+// sizeProjectQuota creates the project-quota of the requested namespace with
+// the requested quota. The NamespaceReconciler leaves it to the request until
+// it is handled; one already there, e.g. from an interrupted attempt, is
+// resized.
+func (r *NamespaceRequestReconciler) sizeProjectQuota(ctx context.Context, project *projectv1.Project, request *projectv1.NamespaceRequest) error {
+	quota := requestedResourceQuota(r.Names, project, request)
+	err := r.Client.Create(ctx, &quota)
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing := corev1.ResourceQuota{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: quota.Name, Namespace: quota.Namespace}, &existing); err != nil {
+		return err
+	}
+	existing.Spec.Hard = quota.Spec.Hard
+	return r.Client.Update(ctx, &existing)
+}
+
+// namespaceRequestOverrun returns the project limits the requested quota
+// would push the project over, checked like a created resourceQuota.
+func namespaceRequestOverrun(names projectv1.Names, project *projectv1.Project, quotas []corev1.ResourceQuota, request *projectv1.NamespaceRequest) []corev1.ResourceName {
+	quota := requestedResourceQuota(names, project, request)
+	all := append(append([]corev1.ResourceQuota{}, quotas...), quota)
+	return budget.Overrun(budget.Allocatable(project.Spec.ProjectLimits, project.Spec.Overcommit), budget.Overcommitted(all, project.Spec.Overcommit), corev1.ResourceList{}, quota.Spec.Hard)
+}
+
+// requestedResourceQuota is the project-quota of the requested namespace: the
+// project's default one, with the requested quota as spec.hard when set.
+func requestedResourceQuota(names projectv1.Names, project *projectv1.Project, request *projectv1.NamespaceRequest) corev1.ResourceQuota {
+	quota := newDefaultResourceQuota(names, request.Spec.Namespace, project.Name)
+	quota.Spec = request.QuotaSpec(project)
+	return quota
+}
+
+func deniedNamespaceRequest(projectName string, message string) projectv1.NamespaceRequestStatus {
+	return projectv1.NamespaceRequestStatus{
+		Phase:   projectv1.NamespaceRequestDenied,
+		Project: projectName,
+		Message: message,
+	}
+}
+
+func (r *NamespaceRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&projectv1.NamespaceRequest{}).
+		Complete(r)
+}
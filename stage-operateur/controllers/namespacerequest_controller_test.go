@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+
+	projectv1 "project/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// quotaRefusingClient refuses every ResourceQuota, as the resourceQuota
+// webhook does with a quota over the limits of an ancestor project.
+type quotaRefusingClient struct {
+	client.Client
+}
+
+func (c quotaRefusingClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	if quota, ok := obj.(*corev1.ResourceQuota); ok {
+		return errors.NewForbidden(schema.GroupResource{Resource: "resourcequotas"}, quota.Name, errors.NewBadRequest("ancestor project exceeded"))
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+var _ = Describe("namespaceRequestOverrun", func() {
+	project := projectv1.Project{
+		ObjectMeta: metav1.ObjectMeta{Name: "project-test1"},
+		Spec: projectv1.ProjectSpec{
+			ProjectLimits: corev1.ResourceList{
+				corev1.ResourceLimitsCPU:    resource.MustParse("10"),
+				corev1.ResourceLimitsMemory: resource.MustParse("10Gi"),
+			},
+		},
+	}
+	quotas := []corev1.ResourceQuota{{
+		ObjectMeta: metav1.ObjectMeta{Name: "project-quota", Namespace: "test1"},
+		Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8"), corev1.ResourceMemory: resource.MustParse("2Gi")}},
+	}}
+	newRequest := func(cpu string) *projectv1.NamespaceRequest {
+		return &projectv1.NamespaceRequest{
+			Spec: projectv1.NamespaceRequestSpec{
+				Namespace: "test2",
+				Quota:     corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse(cpu), corev1.ResourceLimitsMemory: resource.MustParse("1Gi")},
+			},
+		}
+	}
+
+	It("should grant a quota that fits in the project limits", func() {
+		Expect(namespaceRequestOverrun(projectv1.Names{}, &project, quotas, newRequest("2"))).To(BeEmpty())
+	})
+
+	It("should report the limits a quota exceeds", func() {
+		Expect(namespaceRequestOverrun(projectv1.Names{}, &project, quotas, newRequest("3"))).To(Equal([]corev1.ResourceName{corev1.ResourceLimitsCPU}))
+	})
+
+	It("should grant the default quota when none is requested", func() {
+		// Given
+		request := newRequest("0")
+		request.Spec.Quota = nil
+
+		// Then
+		Expect(namespaceRequestOverrun(projectv1.Names{}, &project, quotas, request)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("NamespaceRequestReconciler", func() {
+	It("should delete the namespace it created when its project-quota is refused", func() {
+		// Given
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(projectv1.AddToScheme(scheme)).To(Succeed())
+		requestNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test1", Labels: map[string]string{projectv1.ProjectLabel: "project-1"}}}
+		project := &projectv1.Project{ObjectMeta: metav1.ObjectMeta{Name: "project-1"}}
+		request := &projectv1.NamespaceRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "new-namespace", Namespace: "test1"},
+			Spec:       projectv1.NamespaceRequestSpec{Namespace: "test2"},
+		}
+		c := quotaRefusingClient{fake.NewFakeClientWithScheme(scheme, requestNamespace, project, request)}
+		reconciler := &NamespaceRequestReconciler{Client: c, Log: ctrl.Log, Scheme: scheme}
+		key := types.NamespacedName{Name: "new-namespace", Namespace: "test1"}
+
+		// When
+		_, err := reconciler.Reconcile(ctrl.Request{NamespacedName: key})
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Get(context.Background(), key, request)).To(Succeed())
+		Expect(request.Status.Phase).To(Equal(projectv1.NamespaceRequestDenied))
+		Expect(request.Status.Message).To(ContainSubstring("namespace test2 deleted"))
+		err = c.Get(context.Background(), client.ObjectKey{Name: "test2"}, &corev1.Namespace{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("awaitsNamespaceRequest", func() {
+	newReconciler := func(objects ...runtime.Object) *NamespaceReconciler {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(projectv1.AddToScheme(scheme)).To(Succeed())
+		return &NamespaceReconciler{Client: fake.NewFakeClientWithScheme(scheme, objects...), Log: ctrl.Log, Scheme: scheme}
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test2",
+		Labels:      map[string]string{projectv1.ProjectLabel: "project-1"},
+		Annotations: map[string]string{projectv1.NamespaceRequestAnnotation: "test1/new-namespace"},
+	}}
+
+	It("should leave the project-quota to a request not handled yet", func() {
+		// Given
+		request := &projectv1.NamespaceRequest{ObjectMeta: metav1.ObjectMeta{Name: "new-namespace", Namespace: "test1"}}
+		reconciler := newReconciler(request)
+
+		// When
+		awaited, err := reconciler.awaitsNamespaceRequest(context.Background(), namespace)
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(awaited).To(BeTrue())
+	})
+
+	It("should not wait for a handled or deleted request", func() {
+		// Given
+		request := &projectv1.NamespaceRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "new-namespace", Namespace: "test1"},
+			Status:     projectv1.NamespaceRequestStatus{Phase: projectv1.NamespaceRequestCreated},
+		}
+
+		// Then
+		Expect(newReconciler(request).awaitsNamespaceRequest(context.Background(), namespace)).To(BeFalse())
+		Expect(newReconciler().awaitsNamespaceRequest(context.Background(), namespace)).To(BeFalse())
+	})
+})
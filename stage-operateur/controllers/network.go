@@ -0,0 +1,105 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	projectv1 "project/api/v1"
+)
+
+// isolationPolicyName is the name of the NetworkPolicy isolating a project namespace
+const isolationPolicyName = "project-isolation"
+
+// isolationPolicy returns the NetworkPolicy enforcing the network isolation of
+// the project in the namespace, or nil when the project is not isolated.
+func isolationPolicy(names projectv1.Names, project *projectv1.Project, namespace string) *networkingv1.NetworkPolicy {
+	var peer networkingv1.NetworkPolicyPeer
+	switch project.Spec.NetworkIsolation {
+	case projectv1.NetworkIsolationProject:
+		peer.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{names.Label(): project.Name}}
+	case projectv1.NetworkIsolationNamespace:
+		peer.PodSelector = &metav1.LabelSelector{}
+	default:
+		return nil
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      isolationPolicyName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				projectv1.NetworkIsolationLabel: project.Name,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{From: []networkingv1.NetworkPolicyPeer{peer}},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+		},
+	}
+}
+
+// syncNetworkIsolation keeps the isolation NetworkPolicy of the namespace in
+// sync with the network isolation of the project.
+func (r *NamespaceReconciler) syncNetworkIsolation(ctx context.Context, project *projectv1.Project, namespace string) error {
+	policy := isolationPolicy(r.Names, project, namespace)
+	if policy == nil {
+		return r.deleteIsolationPolicy(ctx, namespace)
+	}
+
+	existing := networkingv1.NetworkPolicy{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: policy.Name}, &existing)
+	if errors.IsNotFound(err) {
+		return r.Client.Create(ctx, policy)
+	}
+	if err != nil {
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(existing.Spec, policy.Spec) && existing.Labels[projectv1.NetworkIsolationLabel] == project.Name {
+		return nil
+	}
+	existing.Spec = policy.Spec
+	if existing.Labels == nil {
+		existing.Labels = map[string]string{}
+	}
+	existing.Labels[projectv1.NetworkIsolationLabel] = project.Name
+	return r.Client.Update(ctx, &existing)
+}
+
+// deleteIsolationPolicy deletes the isolation NetworkPolicy of the namespace,
+// if the operator created it.
+func (r *NamespaceReconciler) deleteIsolationPolicy(ctx context.Context, namespace string) error {
+	existing := networkingv1.NetworkPolicy{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: isolationPolicyName}, &existing)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if _, ok := existing.Labels[projectv1.NetworkIsolationLabel]; !ok {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Client.Delete(ctx, &existing))
+}
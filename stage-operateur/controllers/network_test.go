@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	projectv1 "project/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("isolationPolicy", func() {
+	newProject := func(isolation projectv1.NetworkIsolation) *projectv1.Project {
+		return &projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{Name: "project-test1"},
+			Spec:       projectv1.ProjectSpec{NetworkIsolation: isolation},
+		}
+	}
+
+	It("should not isolate the namespace by default", func() {
+		Expect(isolationPolicy(projectv1.Names{}, newProject(""), "test1")).To(BeNil())
+		Expect(isolationPolicy(projectv1.Names{}, newProject(projectv1.NetworkIsolationNone), "test1")).To(BeNil())
+	})
+
+	It("should only let in the traffic of the project namespaces", func() {
+		// When
+		policy := isolationPolicy(projectv1.Names{}, newProject(projectv1.NetworkIsolationProject), "test1")
+
+		// Then
+		Expect(policy.Namespace).To(Equal("test1"))
+		Expect(policy.Spec.Ingress).To(HaveLen(1))
+		Expect(policy.Spec.Ingress[0].From[0].NamespaceSelector.MatchLabels).To(Equal(map[string]string{"project": "project-test1"}))
+		Expect(policy.Spec.Ingress[0].From[0].PodSelector).To(BeNil())
+	})
+
+	It("should only let in the traffic of the namespace itself", func() {
+		// When
+		policy := isolationPolicy(projectv1.Names{}, newProject(projectv1.NetworkIsolationNamespace), "test1")
+
+		// Then
+		Expect(policy.Spec.Ingress[0].From[0].NamespaceSelector).To(BeNil())
+		Expect(policy.Spec.Ingress[0].From[0].PodSelector).To(Equal(&metav1.LabelSelector{}))
+	})
+})
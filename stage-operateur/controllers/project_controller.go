@@ -18,18 +18,26 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strings"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	projectv1 "project/api/v1"
+	"project/budget"
+	"project/index"
+	"project/metrics"
 )
 
 // ProjectReconciler reconciles a Project object
@@ -37,10 +45,18 @@ type ProjectReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// Recorder, when set, emits an Event on the project when a namespace
+	// joins or leaves it
+	Recorder record.EventRecorder
+	// AccountingMode selects the ResourceQuotas counted against the project limits
+	AccountingMode budget.AccountingMode
+	// Names are the project label and the project-quota name
+	Names projectv1.Names
 }
 
 // +kubebuilder:rbac:groups=project.my.domain,resources=projects,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=project.my.domain,resources=projects/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 func (r *ProjectReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -49,55 +65,389 @@ func (r *ProjectReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	namespaces := corev1.NamespaceList{}
 
 	if err := r.Client.Get(ctx, req.NamespacedName, project); err != nil {
+		if errors.IsNotFound(err) {
+			metrics.DeleteProject(req.Name)
+		}
 		logger.Error(err, "unable to fetch Project")
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	if err := r.List(ctx, &namespaces); err != nil {
+	if !project.DeletionTimestamp.IsZero() {
+		return r.finalizeProject(ctx, logger, project)
+	}
+
+	if !containsString(project.Finalizers, projectv1.ProjectFinalizer) {
+		project.Finalizers = append(project.Finalizers, projectv1.ProjectFinalizer)
+		if err := r.Client.Update(ctx, project); err != nil {
+			logger.Error(err, "unable to add finalizer to Project")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.List(ctx, &namespaces, client.MatchingFields{index.NamespaceProject: project.Name}); err != nil {
 		logger.Error(err, "unable to list namespaces")
 		return ctrl.Result{}, err
 	}
 
-	updateProjectStatus(namespaces, project)
+	projects := projectv1.ProjectList{}
+	if err := r.List(ctx, &projects); err != nil {
+		logger.Error(err, "unable to list projects")
+		return ctrl.Result{}, err
+	}
+
+	previousNamespaces := project.Status.Namespaces
+	updateProjectStatus(r.Names, namespaces, project)
+
+	quotas, err := projectResourceQuotas(ctx, r.Client, r.AccountingMode, r.Names, project.Status.Namespaces)
+	if err != nil {
+		logger.Error(err, "unable to fetch ResourceQuotas")
+		return ctrl.Result{}, err
+	}
+
+	subtreeNamespaces := append([]string{}, project.Status.Namespaces...)
+	for _, name := range projectv1.DescendantProjects(projects.Items, project.Name) {
+		descendantNamespaces := corev1.NamespaceList{}
+		if err := r.List(ctx, &descendantNamespaces, client.MatchingFields{index.NamespaceProject: name}); err != nil {
+			logger.Error(err, "unable to list namespaces")
+			return ctrl.Result{}, err
+		}
+		for _, namespace := range descendantNamespaces.Items {
+			subtreeNamespaces = append(subtreeNamespaces, namespace.Name)
+		}
+	}
+	subtreeQuotas, err := projectResourceQuotas(ctx, r.Client, r.AccountingMode, r.Names, subtreeNamespaces)
+	if err != nil {
+		logger.Error(err, "unable to fetch ResourceQuotas")
+		return ctrl.Result{}, err
+	}
+
+	updateProjectUsage(quotas, project)
+	updateProjectHierarchy(projectv1.ChildProjects(projects.Items, project.Name), subtreeQuotas, project)
+	updateProjectConditions(quotas, project)
+
+	if err := r.Client.Status().Update(ctx, project); err != nil {
+		logger.Error(err, "unable to update Project Status")
+		return ctrl.Result{}, err
+	}
+	metrics.SetProjectBudget(project)
+
+	if r.Recorder != nil {
+		joined, left := namespaceChanges(previousNamespaces, project.Status.Namespaces)
+		for _, name := range joined {
+			r.Recorder.Event(project, corev1.EventTypeNormal, "NamespaceJoined", "namespace "+name+" joined the project")
+		}
+		for _, name := range left {
+			r.Recorder.Event(project, corev1.EventTypeNormal, "NamespaceLeft", "namespace "+name+" left the project")
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// finalizeProject handles the namespaces of a deleted project according to its
+// deletion policy, and releases the project once none is left.
+func (r *ProjectReconciler) finalizeProject(ctx context.Context, logger logr.Logger, project *projectv1.Project) (ctrl.Result, error) {
+	if !containsString(project.Finalizers, projectv1.ProjectFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	namespaces := corev1.NamespaceList{}
+	if err := r.List(ctx, &namespaces, client.MatchingFields{index.NamespaceProject: project.Name}); err != nil {
+		logger.Error(err, "unable to list namespaces")
+		return ctrl.Result{}, err
+	}
 
+	policy := deletionPolicy(project)
+	updateProjectStatus(r.Names, namespaces, project)
+	setProjectCondition(&project.Status.Conditions, deletingCondition(project, policy))
 	if err := r.Client.Status().Update(ctx, project); err != nil {
 		logger.Error(err, "unable to update Project Status")
 		return ctrl.Result{}, err
 	}
 
+	switch {
+	case len(namespaces.Items) == 0:
+	case policy == projectv1.DeletionPolicyRetain:
+		logger.Info("deletion blocked while the project has namespaces", "namespaces", project.Status.Namespaces)
+		return ctrl.Result{}, nil
+	case policy == projectv1.DeletionPolicyDelete:
+		for i := range namespaces.Items {
+			namespace := &namespaces.Items[i]
+			if !namespace.DeletionTimestamp.IsZero() {
+				continue
+			}
+			if err := r.Client.Delete(ctx, namespace); client.IgnoreNotFound(err) != nil {
+				logger.Error(err, "unable to delete Namespace", "namespace", namespace.Name)
+				return ctrl.Result{}, err
+			}
+		}
+		// The project is released once the namespaces are gone
+		return ctrl.Result{}, nil
+	default:
+		for i := range namespaces.Items {
+			if err := r.orphanNamespace(ctx, &namespaces.Items[i]); err != nil {
+				logger.Error(err, "unable to orphan Namespace", "namespace", namespaces.Items[i].Name)
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	project.Finalizers = removeString(project.Finalizers, projectv1.ProjectFinalizer)
+	if err := r.Client.Update(ctx, project); err != nil {
+		logger.Error(err, "unable to remove finalizer from Project")
+		return ctrl.Result{}, err
+	}
 	return ctrl.Result{}, nil
 }
 
-func updateProjectStatus(namespaces corev1.NamespaceList, project *projectv1.Project) {
+// orphanNamespace deletes the project-quota of the namespace, then removes its
+// project label.
+func (r *ProjectReconciler) orphanNamespace(ctx context.Context, namespace *corev1.Namespace) error {
+	quota := corev1.ResourceQuota{}
+	quota.Name = r.Names.QuotaName()
+	quota.Namespace = namespace.Name
+	if err := r.Client.Delete(ctx, &quota); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	delete(namespace.Labels, r.Names.Label())
+	return client.IgnoreNotFound(r.Client.Update(ctx, namespace))
+}
+
+func deletionPolicy(project *projectv1.Project) projectv1.DeletionPolicy {
+	if project.Spec.DeletionPolicy == "" {
+		return projectv1.DeletionPolicyOrphan
+	}
+	return project.Spec.DeletionPolicy
+}
+
+// deletingCondition reports which namespaces are still to be handled before
+// the deleted project is released.
+func deletingCondition(project *projectv1.Project, policy projectv1.DeletionPolicy) projectv1.ProjectCondition {
+	namespaces := project.Status.Namespaces
+	if len(namespaces) == 0 {
+		return projectCondition(project, projectv1.ProjectDeleting, metav1.ConditionTrue,
+			"NamespacesReleased", "the project has no namespace left")
+	}
+
+	message := "namespaces left: " + strings.Join(namespaces, ", ")
+	switch policy {
+	case projectv1.DeletionPolicyRetain:
+		return projectCondition(project, projectv1.ProjectDeleting, metav1.ConditionTrue,
+			"NamespacesRetained", "deletion is blocked until the namespaces are removed from the project, "+message)
+	case projectv1.DeletionPolicyDelete:
+		return projectCondition(project, projectv1.ProjectDeleting, metav1.ConditionTrue,
+			"DeletingNamespaces", message)
+	default:
+		return projectCondition(project, projectv1.ProjectDeleting, metav1.ConditionTrue,
+			"OrphaningNamespaces", message)
+	}
+}
+
+// projectResourceQuotas returns, for every namespace, the quota counted
+// against the project limits.
+func projectResourceQuotas(ctx context.Context, c client.Reader, mode budget.AccountingMode, names projectv1.Names, namespaces []string) ([]corev1.ResourceQuota, error) {
+	quotas := make([]corev1.ResourceQuota, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		namespaceQuotas, err := countedResourceQuotas(ctx, c, mode, names, namespace)
+		if err != nil {
+			return nil, err
+		}
+		quotas = append(quotas, namespaceQuotas...)
+	}
+	return budget.PerNamespace(quotas), nil
+}
+
+// countedResourceQuotas returns the ResourceQuotas of the namespace that count
+// against the project limits.
+func countedResourceQuotas(ctx context.Context, c client.Reader, mode budget.AccountingMode, names projectv1.Names, namespace string) ([]corev1.ResourceQuota, error) {
+	if mode == budget.AllQuotas {
+		quotaList := corev1.ResourceQuotaList{}
+		err := c.List(ctx, &quotaList, client.InNamespace(namespace))
+		return quotaList.Items, err
+	}
+
+	quota := corev1.ResourceQuota{}
+	if err := c.Get(ctx, client.ObjectKey{Name: names.QuotaName(), Namespace: namespace}, &quota); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return []corev1.ResourceQuota{quota}, nil
+}
+
+// namespaceChanges returns the namespaces that joined and left the project
+// between two of its status.namespaces.
+func namespaceChanges(previous []string, current []string) ([]string, []string) {
+	var joined, left []string
+	for _, name := range current {
+		if !containsString(previous, name) {
+			joined = append(joined, name)
+		}
+	}
+	for _, name := range previous {
+		if !containsString(current, name) {
+			left = append(left, name)
+		}
+	}
+	return joined, left
+}
+
+func updateProjectStatus(names projectv1.Names, namespaces corev1.NamespaceList, project *projectv1.Project) {
 	tmp := make([]string, 0, len(namespaces.Items))
 	for _, namespace := range namespaces.Items {
 		labels := namespace.Labels
 		// log.Printf("labels: %v", labels)
-		if labels["project"] == project.Name {
+		if labels[names.Label()] == project.Name {
 			tmp = append(tmp, namespace.Name)
 		}
 	}
 	project.Status.Namespaces = tmp
 }
 
+// updateProjectUsage reports how much of the project limits the namespaces'
+// project-quotas allocate and use, and what is left.
+func updateProjectUsage(quotas []corev1.ResourceQuota, project *projectv1.Project) {
+	limits := project.Spec.ProjectLimits
+
+	project.Status.Allocated = budget.Allocated(limits, budget.Overcommitted(quotas, project.Spec.Overcommit))
+	project.Status.Used = budget.Used(limits, quotas)
+	project.Status.Remaining = budget.Remaining(budget.Allocatable(limits, project.Spec.Overcommit), project.Status.Allocated)
+
+	namespaceQuotas := make([]projectv1.NamespaceQuota, 0, len(quotas))
+	for _, quota := range quotas {
+		namespaceQuotas = append(namespaceQuotas, projectv1.NamespaceQuota{
+			Name: quota.Namespace,
+			Hard: quota.Spec.Hard,
+			Used: quota.Status.Used,
+		})
+	}
+	project.Status.NamespaceQuotas = namespaceQuotas
+}
+
+// nearLimitsPercent is the share of a project limit from which the project
+// is reported as NearLimits.
+const nearLimitsPercent = 90
+
+// updateProjectConditions derives the project conditions from the namespaces,
+// allocation and usage already reported in its status.
+func updateProjectConditions(quotas []corev1.ResourceQuota, project *projectv1.Project) {
+	limits := project.Spec.ProjectLimits
+	allocatable := budget.Allocatable(limits, project.Spec.Overcommit)
+	conditions := &project.Status.Conditions
+
+	withQuota := make(map[string]bool, len(quotas))
+	for _, quota := range quotas {
+		withQuota[quota.Namespace] = true
+	}
+	var missing []string
+	for _, namespace := range project.Status.Namespaces {
+		if !withQuota[namespace] {
+			missing = append(missing, namespace)
+		}
+	}
+	if len(missing) == 0 {
+		setProjectCondition(conditions, projectCondition(project, projectv1.ProjectReady, metav1.ConditionTrue,
+			"DefaultQuotasPresent", "every namespace of the project has its project-quota"))
+	} else {
+		setProjectCondition(conditions, projectCondition(project, projectv1.ProjectReady, metav1.ConditionFalse,
+			"DefaultQuotaMissing", "namespaces without project-quota: "+strings.Join(missing, ", ")))
+	}
+
+	setProjectCondition(conditions, limitsCondition(project, projectv1.ProjectOverCommitted,
+		budget.Above(allocatable, budget.Plus(limits, project.Status.Allocated, project.Status.Carved)), "AllocatedOverLimits", "AllocatedWithinLimits",
+		"project-quotas and children allocate more than the project limits and their overcommit"))
+	setProjectCondition(conditions, limitsCondition(project, projectv1.ProjectNearLimits,
+		budget.Reaching(limits, project.Status.Used, nearLimitsPercent), "UsageNearLimits", "UsageBelowThreshold",
+		fmt.Sprintf("project-quotas use at least %d%% of the project limits", nearLimitsPercent)))
+	setProjectCondition(conditions, limitsCondition(project, projectv1.ProjectLimitsExceeded,
+		budget.Above(limits, project.Status.Used), "UsageOverLimits", "UsageWithinLimits",
+		"project-quotas use more than the project limits"))
+}
+
+func projectCondition(project *projectv1.Project, conditionType string, status metav1.ConditionStatus, reason string, message string) projectv1.ProjectCondition {
+	return projectv1.ProjectCondition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: project.Generation,
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// limitsCondition is true, with the offending resource names in its message,
+// when names is not empty.
+func limitsCondition(project *projectv1.Project, conditionType string, names []corev1.ResourceName, trueReason string, falseReason string, message string) projectv1.ProjectCondition {
+	if len(names) == 0 {
+		return projectCondition(project, conditionType, metav1.ConditionFalse, falseReason, "")
+	}
+	return projectCondition(project, conditionType, metav1.ConditionTrue, trueReason, message+": "+budget.Join(names))
+}
+
 func (r *ProjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	eventHandler := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.namespaceMapFn)}
+	quotaEventHandler := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.resourceQuotaMapFn)}
+	parentEventHandler := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(parentMapFn)}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&projectv1.Project{}).
-		Watches(&source.Kind{Type: &corev1.Namespace{}}, eventHandler).Named("Project").
+		Watches(&source.Kind{Type: &projectv1.Project{}}, parentEventHandler).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, eventHandler).
+		Watches(&source.Kind{Type: &corev1.ResourceQuota{}}, quotaEventHandler).Named("Project").
 		Complete(r)
 }
 
-func (r *ProjectReconciler) namespaceMapFn(handler.MapObject) []reconcile.Request {
-	ctx := context.Background()
+// namespaceMapFn enqueues the project of a namespace. An update maps both the
+// old and the new namespace, so a namespace moving to another project
+// enqueues the project it left and the one it joined.
+func (r *ProjectReconciler) namespaceMapFn(object handler.MapObject) []reconcile.Request {
+	projectName, ok := object.Meta.GetLabels()[r.Names.Label()]
+	if !ok {
+		return []reconcile.Request{}
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: projectName}}}
+}
 
-	ProjectList := &projectv1.ProjectList{}
-	if err := r.List(ctx, ProjectList); err != nil {
+// parentMapFn enqueues the parent of a project so that the budget of its
+// children rolls up to it.
+func parentMapFn(object handler.MapObject) []reconcile.Request {
+	project, ok := object.Object.(*projectv1.Project)
+	if !ok || project.Spec.Parent == "" {
 		return []reconcile.Request{}
 	}
-	requests := make([]reconcile.Request, 0, len(ProjectList.Items))
-	for _, project := range ProjectList.Items {
-		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: project.GetNamespace(), Name: project.GetName()}})
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: project.Spec.Parent}}}
+}
+
+// resourceQuotaMapFn enqueues the project of a counted ResourceQuota so that
+// its allocation and usage are kept up to date.
+func (r *ProjectReconciler) resourceQuotaMapFn(object handler.MapObject) []reconcile.Request {
+	if !r.AccountingMode.Counts(r.Names, object.Meta.GetName()) {
+		return []reconcile.Request{}
+	}
+
+	namespace := corev1.Namespace{}
+	if err := r.Client.Get(context.Background(), client.ObjectKey{Name: object.Meta.GetNamespace()}, &namespace); err != nil {
+		return []reconcile.Request{}
+	}
+	projectName, ok := namespace.Labels[r.Names.Label()]
+	if !ok {
+		return []reconcile.Request{}
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: projectName}}}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, s string) []string {
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if item != s {
+			result = append(result, item)
+		}
 	}
-	return requests
+	return result
 }
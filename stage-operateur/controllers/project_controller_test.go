@@ -1,13 +1,22 @@
 package controllers
 
 import (
+	"context"
+
 	projectv1 "project/api/v1"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 )
 
 var _ = Describe("updateProjectStatus", func() {
@@ -29,7 +38,7 @@ var _ = Describe("updateProjectStatus", func() {
 		}
 
 		// When
-		updateProjectStatus(namespaces, &project)
+		updateProjectStatus(projectv1.Names{}, namespaces, &project)
 
 		// Then
 		Expect(project.Status.Namespaces).To(BeEmpty())
@@ -56,7 +65,7 @@ var _ = Describe("updateProjectStatus", func() {
 		}
 
 		// When
-		updateProjectStatus(namespaces, &project)
+		updateProjectStatus(projectv1.Names{}, namespaces, &project)
 
 		// Then
 		Expect(project.Status.Namespaces).To(ConsistOf("test1"))
@@ -85,7 +94,7 @@ var _ = Describe("updateProjectStatus", func() {
 		}
 
 		// When
-		updateProjectStatus(namespaces, &project)
+		updateProjectStatus(projectv1.Names{}, namespaces, &project)
 
 		// Then
 		Expect(project.Status.Namespaces).To(BeEmpty())
@@ -121,7 +130,7 @@ var _ = Describe("updateProjectStatus", func() {
 		}
 
 		// When
-		updateProjectStatus(namespaces, &project)
+		updateProjectStatus(projectv1.Names{}, namespaces, &project)
 
 		// Then
 		Expect(project.Status.Namespaces).To(ConsistOf("test1", "test2"))
@@ -180,11 +189,347 @@ var _ = Describe("updateProjectStatus", func() {
 		}
 
 		// When
-		updateProjectStatus(namespaces, &project1)
-		updateProjectStatus(namespaces, &project2)
+		updateProjectStatus(projectv1.Names{}, namespaces, &project1)
+		updateProjectStatus(projectv1.Names{}, namespaces, &project2)
 
 		// Then
 		Expect(project1.Status.Namespaces).To(ConsistOf("test1", "test3"))
 		Expect(project2.Status.Namespaces).To(ConsistOf("test2"))
 	})
 })
+
+var _ = Describe("updateProjectUsage", func() {
+	newQuota := func(namespace string, hardCpu string, usedCpu string) corev1.ResourceQuota {
+		return corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "project-quota",
+				Namespace: namespace,
+			},
+			Spec: corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(hardCpu)},
+			},
+			Status: corev1.ResourceQuotaStatus{
+				Hard: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(hardCpu)},
+				Used: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(usedCpu)},
+			},
+		}
+	}
+
+	It("should report allocated, used and remaining for every project limit", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "project-test1",
+			},
+			Spec: projectv1.ProjectSpec{
+				ProjectLimits: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2")},
+			},
+		}
+		quotas := []corev1.ResourceQuota{newQuota("test1", "500m", "100m"), newQuota("test2", "1", "250m")}
+
+		// When
+		updateProjectUsage(quotas, &project)
+
+		// Then
+		allocated := project.Status.Allocated[corev1.ResourceLimitsCPU]
+		used := project.Status.Used[corev1.ResourceLimitsCPU]
+		remaining := project.Status.Remaining[corev1.ResourceLimitsCPU]
+		Expect(allocated.MilliValue()).To(Equal(int64(1500)))
+		Expect(used.MilliValue()).To(Equal(int64(350)))
+		Expect(remaining.MilliValue()).To(Equal(int64(500)))
+	})
+
+	It("should report a negative remaining amount when the project is over-committed", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "project-test1",
+			},
+			Spec: projectv1.ProjectSpec{
+				ProjectLimits: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1")},
+			},
+		}
+		quotas := []corev1.ResourceQuota{newQuota("test1", "1", "0"), newQuota("test2", "1", "0")}
+
+		// When
+		updateProjectUsage(quotas, &project)
+
+		// Then
+		remaining := project.Status.Remaining[corev1.ResourceLimitsCPU]
+		Expect(remaining.Sign()).To(Equal(-1))
+	})
+
+	It("should report the remaining amount of the overcommitted limits", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "project-test1",
+			},
+			Spec: projectv1.ProjectSpec{
+				ProjectLimits: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2")},
+				Overcommit:    corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1.5")},
+			},
+		}
+		quotas := []corev1.ResourceQuota{newQuota("test1", "1", "0"), newQuota("test2", "1500m", "0")}
+		for i := range quotas {
+			quotas[i].Spec.Hard = corev1.ResourceList{corev1.ResourceLimitsCPU: quotas[i].Spec.Hard[corev1.ResourceCPU]}
+		}
+
+		// When
+		updateProjectUsage(quotas, &project)
+
+		// Then
+		remaining := project.Status.Remaining[corev1.ResourceLimitsCPU]
+		Expect(remaining.MilliValue()).To(Equal(int64(500)))
+	})
+
+	It("should not overcommit the short cpu of the project-quotas", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "project-test1",
+			},
+			Spec: projectv1.ProjectSpec{
+				ProjectLimits: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2")},
+				Overcommit:    corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1.5")},
+			},
+		}
+		quotas := []corev1.ResourceQuota{newQuota("test1", "1", "0"), newQuota("test2", "1500m", "0")}
+
+		// When
+		updateProjectUsage(quotas, &project)
+
+		// Then
+		remaining := project.Status.Remaining[corev1.ResourceLimitsCPU]
+		Expect(remaining.MilliValue()).To(Equal(int64(-750)))
+	})
+
+	It("should report the project-quota of every namespace", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "project-test1",
+			},
+		}
+		quotas := []corev1.ResourceQuota{newQuota("test1", "1", "0"), newQuota("test2", "2", "1")}
+
+		// When
+		updateProjectUsage(quotas, &project)
+
+		// Then
+		Expect(project.Status.NamespaceQuotas).To(HaveLen(2))
+		Expect(project.Status.NamespaceQuotas[0].Name).To(Equal("test1"))
+		Expect(project.Status.NamespaceQuotas[1].Name).To(Equal("test2"))
+		used := project.Status.NamespaceQuotas[1].Used[corev1.ResourceCPU]
+		Expect(used.Value()).To(Equal(int64(1)))
+	})
+})
+
+var _ = Describe("updateProjectConditions", func() {
+	newProject := func(limitCpu string) projectv1.Project {
+		return projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "project-test1",
+			},
+			Spec: projectv1.ProjectSpec{
+				ProjectLimits: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse(limitCpu)},
+			},
+			Status: projectv1.ProjectStatus{Namespaces: []string{"test1", "test2"}},
+		}
+	}
+	newQuota := func(namespace string, hardCpu string, usedCpu string) corev1.ResourceQuota {
+		return corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "project-quota", Namespace: namespace},
+			Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(hardCpu)}},
+			Status:     corev1.ResourceQuotaStatus{Used: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(usedCpu)}},
+		}
+	}
+	statusOf := func(project projectv1.Project, conditionType string) metav1.ConditionStatus {
+		condition := findProjectCondition(project.Status.Conditions, conditionType)
+		Expect(condition).NotTo(BeNil())
+		return condition.Status
+	}
+
+	It("should be ready and healthy when every namespace has a quota within the limits", func() {
+		// Given
+		project := newProject("4")
+		quotas := []corev1.ResourceQuota{newQuota("test1", "1", "100m"), newQuota("test2", "1", "100m")}
+
+		// When
+		updateProjectUsage(quotas, &project)
+		updateProjectConditions(quotas, &project)
+
+		// Then
+		Expect(statusOf(project, projectv1.ProjectReady)).To(Equal(metav1.ConditionTrue))
+		Expect(statusOf(project, projectv1.ProjectOverCommitted)).To(Equal(metav1.ConditionFalse))
+		Expect(statusOf(project, projectv1.ProjectNearLimits)).To(Equal(metav1.ConditionFalse))
+		Expect(statusOf(project, projectv1.ProjectLimitsExceeded)).To(Equal(metav1.ConditionFalse))
+	})
+
+	It("should not be ready when a namespace has no project-quota", func() {
+		// Given
+		project := newProject("4")
+		quotas := []corev1.ResourceQuota{newQuota("test1", "1", "0")}
+
+		// When
+		updateProjectUsage(quotas, &project)
+		updateProjectConditions(quotas, &project)
+
+		// Then
+		ready := findProjectCondition(project.Status.Conditions, projectv1.ProjectReady)
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Message).To(ContainSubstring("test2"))
+	})
+
+	It("should be over-committed, near and over the limits when limits were lowered", func() {
+		// Given
+		project := newProject("1")
+		quotas := []corev1.ResourceQuota{newQuota("test1", "1", "600m"), newQuota("test2", "1", "600m")}
+
+		// When
+		updateProjectUsage(quotas, &project)
+		updateProjectConditions(quotas, &project)
+
+		// Then
+		overCommitted := findProjectCondition(project.Status.Conditions, projectv1.ProjectOverCommitted)
+		Expect(overCommitted.Status).To(Equal(metav1.ConditionTrue))
+		Expect(overCommitted.Message).To(ContainSubstring("limits.cpu"))
+		Expect(statusOf(project, projectv1.ProjectNearLimits)).To(Equal(metav1.ConditionTrue))
+		Expect(statusOf(project, projectv1.ProjectLimitsExceeded)).To(Equal(metav1.ConditionTrue))
+	})
+
+	It("should be near the limits when usage reaches the threshold", func() {
+		// Given
+		project := newProject("1")
+		quotas := []corev1.ResourceQuota{newQuota("test1", "500m", "450m"), newQuota("test2", "500m", "450m")}
+
+		// When
+		updateProjectUsage(quotas, &project)
+		updateProjectConditions(quotas, &project)
+
+		// Then
+		Expect(statusOf(project, projectv1.ProjectOverCommitted)).To(Equal(metav1.ConditionFalse))
+		Expect(statusOf(project, projectv1.ProjectNearLimits)).To(Equal(metav1.ConditionTrue))
+		Expect(statusOf(project, projectv1.ProjectLimitsExceeded)).To(Equal(metav1.ConditionFalse))
+	})
+})
+
+var _ = Describe("deletingCondition", func() {
+	It("should default to the Orphan policy", func() {
+		// Given
+		project := projectv1.Project{}
+
+		// Then
+		Expect(deletionPolicy(&project)).To(Equal(projectv1.DeletionPolicyOrphan))
+	})
+
+	It("should report the namespaces retained by the project", func() {
+		// Given
+		project := projectv1.Project{
+			Spec:   projectv1.ProjectSpec{DeletionPolicy: projectv1.DeletionPolicyRetain},
+			Status: projectv1.ProjectStatus{Namespaces: []string{"test1", "test2"}},
+		}
+
+		// When
+		condition := deletingCondition(&project, deletionPolicy(&project))
+
+		// Then
+		Expect(condition.Type).To(Equal(projectv1.ProjectDeleting))
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal("NamespacesRetained"))
+		Expect(condition.Message).To(ContainSubstring("test1, test2"))
+	})
+
+	It("should report that no namespace is left", func() {
+		// Given
+		project := projectv1.Project{
+			Spec: projectv1.ProjectSpec{DeletionPolicy: projectv1.DeletionPolicyDelete},
+		}
+
+		// When
+		condition := deletingCondition(&project, deletionPolicy(&project))
+
+		// Then
+		Expect(condition.Reason).To(Equal("NamespacesReleased"))
+	})
+})
+
+var _ = Describe("removeString", func() {
+	It("should only remove the given finalizer", func() {
+		// Given
+		finalizers := []string{"other", projectv1.ProjectFinalizer}
+
+		// When
+		result := removeString(finalizers, projectv1.ProjectFinalizer)
+
+		// Then
+		Expect(result).To(Equal([]string{"other"}))
+		Expect(containsString(result, projectv1.ProjectFinalizer)).To(BeFalse())
+	})
+})
+
+var _ = Describe("namespaceMapFn", func() {
+	It("should enqueue only the project of the namespace", func() {
+		// Given
+		namespace := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "namespace-1", Labels: map[string]string{"project": "project-1"}}}
+
+		// When
+		requests := (&ProjectReconciler{}).namespaceMapFn(handler.MapObject{Meta: &namespace, Object: &namespace})
+
+		// Then
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal("project-1"))
+	})
+
+	It("should enqueue nothing for a namespace without project", func() {
+		// Given
+		namespace := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "namespace-1"}}
+
+		// When
+		requests := (&ProjectReconciler{}).namespaceMapFn(handler.MapObject{Meta: &namespace, Object: &namespace})
+
+		// Then
+		Expect(requests).To(BeEmpty())
+	})
+})
+
+var _ = Describe("namespaceChanges", func() {
+	It("should return the namespaces that joined and left the project", func() {
+		// When
+		joined, left := namespaceChanges([]string{"namespace-1", "namespace-2"}, []string{"namespace-2", "namespace-3"})
+
+		// Then
+		Expect(joined).To(Equal([]string{"namespace-3"}))
+		Expect(left).To(Equal([]string{"namespace-1"}))
+	})
+
+	It("should return nothing when the namespaces did not change", func() {
+		// When
+		joined, left := namespaceChanges([]string{"namespace-1"}, []string{"namespace-1"})
+
+		// Then
+		Expect(joined).To(BeEmpty())
+		Expect(left).To(BeEmpty())
+	})
+})
+
+var _ = Describe("ProjectReconciler", func() {
+	It("should report a namespace joining the project without a Recorder", func() {
+		// Given
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(projectv1.AddToScheme(scheme)).To(Succeed())
+		project := &projectv1.Project{ObjectMeta: metav1.ObjectMeta{Name: "project-1"}}
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test1", Labels: map[string]string{projectv1.ProjectLabel: "project-1"}}}
+		c := fake.NewFakeClientWithScheme(scheme, project, namespace)
+		reconciler := &ProjectReconciler{Client: c, Log: ctrl.Log, Scheme: scheme}
+
+		// When
+		_, err := reconciler.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Name: "project-1"}})
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Get(context.Background(), types.NamespacedName{Name: "project-1"}, project)).To(Succeed())
+		Expect(project.Status.Namespaces).To(ConsistOf("test1"))
+	})
+})
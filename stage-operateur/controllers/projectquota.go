@@ -0,0 +1,217 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	projectv1 "project/api/v1"
+	"project/budget"
+	"project/index"
+)
+
+// syncProjectQuota creates the project-quota of the namespace, or repairs the
+// existing one: its labels are restored, it follows the project's
+// defaultNamespaceQuota as long as nobody resized it, and the namespace
+// condition ProjectQuotaOverLimits flags it when it no longer fits the project.
+// The project-quota of a namespace created for a NamespaceRequest is left to
+// the NamespaceRequestReconciler until the request is handled.
+func (r *NamespaceReconciler) syncProjectQuota(ctx context.Context, namespace *corev1.Namespace, project *projectv1.Project) error {
+	quotas, err := r.otherProjectQuotas(ctx, namespace.Name, project.Name)
+	if err != nil {
+		return err
+	}
+
+	quota := corev1.ResourceQuota{}
+	err = r.Client.Get(ctx, client.ObjectKey{Name: r.Names.QuotaName(), Namespace: namespace.Name}, &quota)
+	if errors.IsNotFound(err) {
+		awaited, err := r.awaitsNamespaceRequest(ctx, namespace)
+		if err != nil || awaited {
+			return err
+		}
+		return r.createProjectQuota(ctx, namespace, project, quotas)
+	}
+	if err != nil {
+		return err
+	}
+
+	changed := restoreProjectQuotaLabels(r.Names, &quota, project.Name)
+	followed := false
+
+	conditions := []corev1.NamespaceCondition{}
+	hard := project.NamespaceQuotaSpec().Hard
+	if followsDefaultQuota(&quota) && quota.Annotations[projectv1.DefaultQuotaHashAnnotation] != quotaHash(hard) {
+		candidate := *quota.DeepCopy()
+		candidate.Spec.Hard = hard
+		exceeded := budget.Overrun(budget.Allocatable(project.Spec.ProjectLimits, project.Spec.Overcommit), budget.Overcommitted(append(quotas, candidate), project.Spec.Overcommit), quota.Spec.Hard, hard)
+		if len(exceeded) == 0 {
+			quota.Spec.Hard = hard
+			setDefaultQuotaHash(&quota)
+			changed = true
+			followed = true
+		}
+		if len(exceeded) > 0 || hasNamespaceCondition(namespace, projectv1.NamespaceDefaultQuotaReduced) {
+			conditions = append(conditions, defaultQuotaReducedCondition(project.Name, exceeded))
+		}
+	}
+
+	if changed {
+		if err := r.Client.Update(ctx, &quota); err != nil {
+			return err
+		}
+		if followed && r.Recorder != nil {
+			r.Recorder.Event(project, corev1.EventTypeNormal, "DefaultQuotaUpdated", "project-quota of namespace "+namespace.Name+" updated to the defaultNamespaceQuota")
+		}
+	}
+
+	overLimits := projectQuotaOverLimits(budget.Allocatable(project.Spec.ProjectLimits, project.Spec.Overcommit), project.Spec.Overcommit, quotas, quota)
+	if len(overLimits) > 0 || hasNamespaceCondition(namespace, projectv1.NamespaceProjectQuotaOverLimits) {
+		conditions = append(conditions, projectQuotaOverLimitsCondition(project.Name, overLimits))
+	}
+
+	updated := false
+	for _, condition := range conditions {
+		if setNamespaceCondition(namespace, condition) {
+			updated = true
+		}
+	}
+	if updated {
+		return r.Client.Status().Update(ctx, namespace)
+	}
+	return nil
+}
+
+// awaitsNamespaceRequest reports whether the namespace was created for a
+// NamespaceRequest not handled yet, which creates the project-quota with the
+// requested quota.
+func (r *NamespaceReconciler) awaitsNamespaceRequest(ctx context.Context, namespace *corev1.Namespace) (bool, error) {
+	key, ok := projectv1.NamespaceRequestOf(namespace)
+	if !ok {
+		return false, nil
+	}
+	request := projectv1.NamespaceRequest{}
+	err := r.Client.Get(ctx, key, &request)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return request.Status.Phase != projectv1.NamespaceRequestCreated, nil
+}
+
+// otherProjectQuotas returns the ResourceQuotas counted against the project
+// limits in the namespaces of the project but the given one.
+func (r *NamespaceReconciler) otherProjectQuotas(ctx context.Context, namespaceName string, projectName string) ([]corev1.ResourceQuota, error) {
+	namespaces := corev1.NamespaceList{}
+	if err := r.Client.List(ctx, &namespaces, client.MatchingFields{index.NamespaceProject: projectName}); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(namespaces.Items))
+	for _, item := range namespaces.Items {
+		if item.Name != namespaceName {
+			names = append(names, item.Name)
+		}
+	}
+	return projectResourceQuotas(ctx, r.Client, r.AccountingMode, r.Names, names)
+}
+
+// restoreProjectQuotaLabels puts back the labels of a project-quota and
+// reports whether any was missing or wrong.
+func restoreProjectQuotaLabels(names projectv1.Names, quota *corev1.ResourceQuota, projectName string) bool {
+	if quota.Labels == nil {
+		quota.Labels = map[string]string{}
+	}
+	changed := false
+	for key, value := range map[string]string{names.Label(): projectName, projectv1.ProjectQuotaManagedLabel: "true"} {
+		if quota.Labels[key] != value {
+			quota.Labels[key] = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// followsDefaultQuota reports whether the project-quota still has the
+// spec.hard it was given from the project's default quota.
+func followsDefaultQuota(quota *corev1.ResourceQuota) bool {
+	hash, ok := quota.Annotations[projectv1.DefaultQuotaHashAnnotation]
+	return ok && hash == quotaHash(quota.Spec.Hard)
+}
+
+// setDefaultQuotaHash records that the spec.hard of the project-quota comes
+// from the project's default quota.
+func setDefaultQuotaHash(quota *corev1.ResourceQuota) {
+	if quota.Annotations == nil {
+		quota.Annotations = map[string]string{}
+	}
+	quota.Annotations[projectv1.DefaultQuotaHashAnnotation] = quotaHash(quota.Spec.Hard)
+}
+
+// quotaHash is a hash of the amounts of hard that does not depend on the
+// order of the resources.
+func quotaHash(hard corev1.ResourceList) string {
+	names := make([]string, 0, len(hard))
+	for name := range hard {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		amount := hard[corev1.ResourceName(name)]
+		hash.Write([]byte(name + "=" + amount.String() + ";"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// projectQuotaOverLimits returns the project limits that are exceeded and
+// that the project-quota takes a part of.
+func projectQuotaOverLimits(limits corev1.ResourceList, overcommit corev1.ResourceList, quotas []corev1.ResourceQuota, quota corev1.ResourceQuota) []corev1.ResourceName {
+	overLimits := []corev1.ResourceName{}
+	for _, name := range budget.Exceeded(limits, budget.Overcommitted(append(quotas, quota), overcommit)) {
+		amount := budget.Amount(quota.Spec.Hard, name)
+		if amount.Sign() > 0 {
+			overLimits = append(overLimits, name)
+		}
+	}
+	return overLimits
+}
+
+func projectQuotaOverLimitsCondition(projectName string, overLimits []corev1.ResourceName) corev1.NamespaceCondition {
+	if len(overLimits) == 0 {
+		return corev1.NamespaceCondition{
+			Type:    projectv1.NamespaceProjectQuotaOverLimits,
+			Status:  corev1.ConditionFalse,
+			Reason:  "WithinProjectLimits",
+			Message: "project-quota fits in the limits of Project " + projectName,
+		}
+	}
+	return corev1.NamespaceCondition{
+		Type:    projectv1.NamespaceProjectQuotaOverLimits,
+		Status:  corev1.ConditionTrue,
+		Reason:  "ProjectLimitsExceeded",
+		Message: "project-quota takes part in exceeding the limits of Project " + projectName + ": " + budget.Join(overLimits),
+	}
+}
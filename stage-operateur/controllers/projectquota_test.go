@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	projectv1 "project/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+var _ = Describe("restoreProjectQuotaLabels", func() {
+	It("should put back stripped labels", func() {
+		// Given
+		quota := corev1.ResourceQuota{ObjectMeta: v1.ObjectMeta{Name: "project-quota", Labels: map[string]string{"team": "a"}}}
+
+		// When
+		changed := restoreProjectQuotaLabels(projectv1.Names{}, &quota, "project-1")
+
+		// Then
+		Expect(changed).To(BeTrue())
+		Expect(quota.Labels).To(Equal(map[string]string{
+			"team":                             "a",
+			"project":                          "project-1",
+			projectv1.ProjectQuotaManagedLabel: "true",
+		}))
+	})
+
+	It("should leave a labelled project-quota untouched", func() {
+		// Given
+		quota := newDefaultResourceQuota(projectv1.Names{}, "namespace-1", "project-1")
+
+		// When
+		changed := restoreProjectQuotaLabels(projectv1.Names{}, &quota, "project-1")
+
+		// Then
+		Expect(changed).To(BeFalse())
+	})
+})
+
+var _ = Describe("followsDefaultQuota", func() {
+	It("should follow the default quota until spec.hard is changed", func() {
+		// Given
+		quota := newDefaultResourceQuota(projectv1.Names{}, "namespace-1", "project-1")
+		setDefaultQuotaHash(&quota)
+
+		// Then
+		Expect(followsDefaultQuota(&quota)).To(BeTrue())
+
+		// When
+		quota.Spec.Hard[corev1.ResourceLimitsCPU] = resource.MustParse("1")
+
+		// Then
+		Expect(followsDefaultQuota(&quota)).To(BeFalse())
+	})
+
+	It("should not follow the default quota without the hash annotation", func() {
+		// Given
+		quota := newDefaultResourceQuota(projectv1.Names{}, "namespace-1", "project-1")
+
+		// Then
+		Expect(followsDefaultQuota(&quota)).To(BeFalse())
+	})
+})
+
+var _ = Describe("quotaHash", func() {
+	It("should not depend on how the amounts are written", func() {
+		// Given
+		a := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1000m"), corev1.ResourceLimitsMemory: resource.MustParse("1Gi")}
+		b := corev1.ResourceList{corev1.ResourceLimitsMemory: resource.MustParse("1Gi"), corev1.ResourceLimitsCPU: resource.MustParse("1")}
+
+		// Then
+		Expect(quotaHash(a)).To(Equal(quotaHash(b)))
+		Expect(quotaHash(a)).NotTo(Equal(quotaHash(corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1")})))
+	})
+})
+
+var _ = Describe("projectQuotaOverLimits", func() {
+	limits := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2"), corev1.ResourceLimitsMemory: resource.MustParse("2Gi")}
+	others := []corev1.ResourceQuota{{Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{
+		corev1.ResourceLimitsCPU:    resource.MustParse("2"),
+		corev1.ResourceLimitsMemory: resource.MustParse("3Gi"),
+	}}}}
+
+	It("should return the exceeded limits the project-quota takes a part of", func() {
+		// Given
+		quota := corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{
+			corev1.ResourceLimitsCPU:    resource.MustParse("1"),
+			corev1.ResourceLimitsMemory: resource.MustParse("0"),
+		}}}
+
+		// When
+		overLimits := projectQuotaOverLimits(limits, nil, others, quota)
+
+		// Then
+		Expect(overLimits).To(Equal([]corev1.ResourceName{corev1.ResourceLimitsCPU}))
+	})
+
+	It("should return nothing when the project fits in its limits", func() {
+		// When
+		overLimits := projectQuotaOverLimits(limits, nil, nil, newDefaultResourceQuota(projectv1.Names{}, "namespace-1", "project-1"))
+
+		// Then
+		Expect(overLimits).To(BeEmpty())
+	})
+})
+
+var _ = Describe("projectQuotaOverLimitsCondition", func() {
+	It("should list the exceeded limits", func() {
+		// When
+		condition := projectQuotaOverLimitsCondition("project-1", []corev1.ResourceName{corev1.ResourceLimitsCPU})
+
+		// Then
+		Expect(condition.Type).To(Equal(projectv1.NamespaceProjectQuotaOverLimits))
+		Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+		Expect(condition.Message).To(Equal("project-quota takes part in exceeding the limits of Project project-1: limits.cpu"))
+	})
+})
+
+var _ = Describe("projectQuotaMapFn", func() {
+	It("should enqueue the namespace of a project-quota, labelled or not", func() {
+		// Given
+		quota := corev1.ResourceQuota{ObjectMeta: v1.ObjectMeta{Name: "project-quota", Namespace: "namespace-1"}}
+
+		// When
+		requests := (&NamespaceReconciler{}).projectQuotaMapFn(handler.MapObject{Meta: &quota, Object: &quota})
+
+		// Then
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal("namespace-1"))
+	})
+
+	It("should ignore other ResourceQuotas", func() {
+		// Given
+		quota := corev1.ResourceQuota{ObjectMeta: v1.ObjectMeta{Name: "other", Namespace: "namespace-1"}}
+
+		// When
+		requests := (&NamespaceReconciler{}).projectQuotaMapFn(handler.MapObject{Meta: &quota, Object: &quota})
+
+		// Then
+		Expect(requests).To(BeEmpty())
+	})
+})
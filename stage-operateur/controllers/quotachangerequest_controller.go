@@ -0,0 +1,261 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	projectv1 "project/api/v1"
+)
+
+// QuotaChangeRequestReconciler reconciles a QuotaChangeRequest object
+type QuotaChangeRequestReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// Names are the project label and the project-quota name
+	Names projectv1.Names
+}
+
+// +kubebuilder:rbac:groups=project.my.domain,resources=quotachangerequests,verbs=get;list;watch
+// +kubebuilder:rbac:groups=project.my.domain,resources=quotachangerequests/status,verbs=get;update;patch
+
+func (r *QuotaChangeRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	logger := r.Log.WithValues("quotachangerequest", req.NamespacedName)
+
+	request := &projectv1.QuotaChangeRequest{}
+	if err := r.Client.Get(ctx, req.NamespacedName, request); err != nil {
+		logger.Error(err, "unable to fetch QuotaChangeRequest")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	switch request.Status.Phase {
+	case projectv1.QuotaChangeApplied, projectv1.QuotaChangeRejected, projectv1.QuotaChangeFailed:
+		return ctrl.Result{}, nil
+	}
+
+	status := request.Status.DeepCopy()
+	if len(status.History) == 0 {
+		recordQuotaChange(status, "Requested", request.Annotations[projectv1.QuotaChangeRequestedByAnnotation], request.Spec.Reason)
+	}
+
+	project, err := r.projectOfRequest(ctx, request)
+	if err != nil {
+		logger.Error(err, "unable to get the project of the request")
+		return ctrl.Result{}, err
+	}
+
+	decidedBy := request.Annotations[projectv1.QuotaChangeDecidedByAnnotation]
+	switch {
+	case project == nil:
+		failQuotaChange(status, "namespace "+request.Namespace+" does not belong to a project")
+	case status.Phase == projectv1.QuotaChangeInProgress:
+		// the target is already recorded, the project-quota is updated below
+	case request.Spec.Decision == projectv1.QuotaChangeDecisionRejected:
+		status.Phase = projectv1.QuotaChangeRejected
+		status.Message = "rejected by a project admin"
+		recordQuotaChange(status, "Rejected", decidedBy, "")
+	case request.Spec.Decision == projectv1.QuotaChangeDecisionApproved:
+		recordQuotaChange(status, "Approved", decidedBy, "")
+		err = r.targetQuotaChange(ctx, request, status)
+	case autoApproved(project.Spec.QuotaAutoApproval, request.Spec.Delta):
+		recordQuotaChange(status, "AutoApproved", "", "the delta is below the auto-approval threshold of the project")
+		err = r.targetQuotaChange(ctx, request, status)
+	default:
+		status.Phase = projectv1.QuotaChangePending
+		status.Message = "waiting for the decision of a project admin"
+	}
+	if err != nil {
+		logger.Error(err, "unable to apply the quota change")
+		return ctrl.Result{}, err
+	}
+
+	if status.Phase == projectv1.QuotaChangeInProgress {
+		// The target is recorded before the project-quota is updated, so that
+		// a request reconciled again after the update does not add its delta
+		// a second time.
+		if request.Status.Phase != projectv1.QuotaChangeInProgress {
+			request.Status = *status
+			if err := r.Client.Status().Update(ctx, request); err != nil {
+				logger.Error(err, "unable to update QuotaChangeRequest Status")
+				return ctrl.Result{}, err
+			}
+			status = request.Status.DeepCopy()
+		}
+		if err := r.applyQuotaChange(ctx, request, status); err != nil {
+			logger.Error(err, "unable to apply the quota change")
+			return ctrl.Result{}, err
+		}
+	}
+
+	request.Status = *status
+	if err := r.Client.Status().Update(ctx, request); err != nil {
+		logger.Error(err, "unable to update QuotaChangeRequest Status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// projectOfRequest returns the project of the namespace of the request, or
+// nil when it does not belong to an existing project.
+func (r *QuotaChangeRequestReconciler) projectOfRequest(ctx context.Context, request *projectv1.QuotaChangeRequest) (*projectv1.Project, error) {
+	namespace := corev1.Namespace{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: request.Namespace}, &namespace); err != nil {
+		return nil, err
+	}
+	projectName, ok := namespace.Labels[r.Names.Label()]
+	if !ok {
+		return nil, nil
+	}
+
+	project := projectv1.Project{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: projectName}, &project)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// targetQuotaChange records in the status the spec.hard of the project-quota
+// of the namespace of the request, and the one once its delta added, and puts
+// the request InProgress.
+func (r *QuotaChangeRequestReconciler) targetQuotaChange(ctx context.Context, request *projectv1.QuotaChangeRequest, status *projectv1.QuotaChangeRequestStatus) error {
+	quota := corev1.ResourceQuota{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: r.Names.QuotaName(), Namespace: request.Namespace}, &quota)
+	if errors.IsNotFound(err) {
+		failQuotaChange(status, "namespace "+request.Namespace+" has no project-quota")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	hard, err := applyDelta(quota.Spec.Hard, request.Spec.Delta)
+	if err != nil {
+		failQuotaChange(status, err.Error())
+		return nil
+	}
+	status.Phase = projectv1.QuotaChangeInProgress
+	status.Message = "updating the project-quota of namespace " + request.Namespace
+	status.AppliedHard = hard
+	status.BaseHard = quota.Spec.Hard.DeepCopy()
+	return nil
+}
+
+// applyQuotaChange sets the project-quota of the namespace of the request to
+// the spec.hard recorded in the status. A project-quota already there is
+// left untouched, one changed since the delta was added to it fails the
+// request rather than losing the change. The update goes through the
+// resourceQuota webhook, so a change over the project limits fails.
+func (r *QuotaChangeRequestReconciler) applyQuotaChange(ctx context.Context, request *projectv1.QuotaChangeRequest, status *projectv1.QuotaChangeRequestStatus) error {
+	quota := corev1.ResourceQuota{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: r.Names.QuotaName(), Namespace: request.Namespace}, &quota)
+	if errors.IsNotFound(err) {
+		failQuotaChange(status, "namespace "+request.Namespace+" has no project-quota")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !equality.Semantic.DeepEqual(quota.Spec.Hard, status.AppliedHard) {
+		if !equality.Semantic.DeepEqual(quota.Spec.Hard, status.BaseHard) {
+			failQuotaChange(status, "project-quota of namespace "+request.Namespace+" changed since the delta was added to it, request the change again")
+			return nil
+		}
+		quota.Spec.Hard = status.AppliedHard
+		if err := r.Client.Update(ctx, &quota); err != nil {
+			if errors.IsForbidden(err) || errors.IsInvalid(err) {
+				failQuotaChange(status, err.Error())
+				return nil
+			}
+			return err
+		}
+	}
+
+	status.Phase = projectv1.QuotaChangeApplied
+	status.Message = "project-quota of namespace " + request.Namespace + " updated"
+	recordQuotaChange(status, "Applied", "", "")
+	return nil
+}
+
+// applyDelta returns hard with the delta added. No resource can become negative.
+func applyDelta(hard corev1.ResourceList, delta corev1.ResourceList) (corev1.ResourceList, error) {
+	result := hard.DeepCopy()
+	if result == nil {
+		result = corev1.ResourceList{}
+	}
+	for name, amount := range delta {
+		total := result[name]
+		total.Add(amount)
+		if total.Sign() < 0 {
+			return nil, fmt.Errorf("%s would be negative", name)
+		}
+		result[name] = total
+	}
+	return result, nil
+}
+
+// autoApproved reports whether no resource of the delta increases by more
+// than the auto-approval threshold. Decreases are always approved.
+func autoApproved(threshold corev1.ResourceList, delta corev1.ResourceList) bool {
+	for name, amount := range delta {
+		if amount.Sign() <= 0 {
+			continue
+		}
+		limit, ok := threshold[name]
+		if !ok || amount.Cmp(limit) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func failQuotaChange(status *projectv1.QuotaChangeRequestStatus, message string) {
+	status.Phase = projectv1.QuotaChangeFailed
+	status.Message = message
+	recordQuotaChange(status, "Failed", "", message)
+}
+
+func recordQuotaChange(status *projectv1.QuotaChangeRequestStatus, action string, user string, message string) {
+	status.History = append(status.History, projectv1.QuotaChangeRecord{
+		Time:    metav1.Now(),
+		Action:  action,
+		User:    user,
+		Message: message,
+	})
+}
+
+func (r *QuotaChangeRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&projectv1.QuotaChangeRequest{}).
+		Complete(r)
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	projectv1 "project/api/v1"
+)
+
+var _ = Describe("applyDelta", func() {
+	hard := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2"), corev1.ResourceLimitsMemory: resource.MustParse("1Gi")}
+
+	It("should add the delta to the hard limits", func() {
+		// Given
+		delta := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("500m"), corev1.ResourcePods: resource.MustParse("10")}
+
+		// When
+		result, err := applyDelta(hard, delta)
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		cpu := result[corev1.ResourceLimitsCPU]
+		pods := result[corev1.ResourcePods]
+		Expect(cpu.MilliValue()).To(Equal(int64(2500)))
+		Expect(pods.Value()).To(Equal(int64(10)))
+		Expect(hard).NotTo(HaveKey(corev1.ResourcePods))
+	})
+
+	It("should refuse to make a resource negative", func() {
+		// Given
+		delta := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("-3")}
+
+		// When
+		_, err := applyDelta(hard, delta)
+
+		// Then
+		Expect(err).To(MatchError("limits.cpu would be negative"))
+	})
+})
+
+var _ = Describe("autoApproved", func() {
+	threshold := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1")}
+
+	It("should approve increases up to the threshold and any decrease", func() {
+		Expect(autoApproved(threshold, corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1")})).To(BeTrue())
+		Expect(autoApproved(threshold, corev1.ResourceList{corev1.ResourceLimitsMemory: resource.MustParse("-1Gi")})).To(BeTrue())
+	})
+
+	It("should not approve increases over the threshold or of other resources", func() {
+		Expect(autoApproved(threshold, corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1100m")})).To(BeFalse())
+		Expect(autoApproved(threshold, corev1.ResourceList{corev1.ResourceLimitsMemory: resource.MustParse("1Gi")})).To(BeFalse())
+		Expect(autoApproved(nil, corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1m")})).To(BeFalse())
+	})
+})
+
+var _ = Describe("QuotaChangeRequestReconciler", func() {
+	key := types.NamespacedName{Name: "more-cpu", Namespace: "test1"}
+
+	// reconcile runs the reconciler once on the request and returns the
+	// request and the project-quota as they are left.
+	reconcile := func(request *projectv1.QuotaChangeRequest, quotaHard corev1.ResourceList) (projectv1.QuotaChangeRequest, corev1.ResourceQuota) {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(projectv1.AddToScheme(scheme)).To(Succeed())
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test1", Labels: map[string]string{projectv1.ProjectLabel: "project-1"}}}
+		project := &projectv1.Project{ObjectMeta: metav1.ObjectMeta{Name: "project-1"}}
+		quota := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: projectv1.ProjectQuotaName, Namespace: "test1"}, Spec: corev1.ResourceQuotaSpec{Hard: quotaHard}}
+		c := fake.NewFakeClientWithScheme(scheme, namespace, project, quota, request)
+		reconciler := &QuotaChangeRequestReconciler{Client: c, Log: ctrl.Log, Scheme: scheme}
+
+		_, err := reconciler.Reconcile(ctrl.Request{NamespacedName: key})
+
+		Expect(err).NotTo(HaveOccurred())
+		result := projectv1.QuotaChangeRequest{}
+		Expect(c.Get(context.Background(), key, &result)).To(Succeed())
+		Expect(c.Get(context.Background(), client.ObjectKey{Name: projectv1.ProjectQuotaName, Namespace: "test1"}, quota)).To(Succeed())
+		return result, *quota
+	}
+
+	newRequest := func() *projectv1.QuotaChangeRequest {
+		return &projectv1.QuotaChangeRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: projectv1.QuotaChangeRequestSpec{
+				Delta:    corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1")},
+				Decision: projectv1.QuotaChangeDecisionApproved,
+			},
+		}
+	}
+
+	It("should add the delta of an approved request to the project-quota", func() {
+		// Given
+		request := newRequest()
+
+		// When
+		result, quota := reconcile(request, corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2")})
+
+		// Then
+		Expect(result.Status.Phase).To(Equal(projectv1.QuotaChangeApplied))
+		Expect(result.Status.BaseHard).To(HaveKeyWithValue(corev1.ResourceLimitsCPU, resource.MustParse("2")))
+		cpu := quota.Spec.Hard[corev1.ResourceLimitsCPU]
+		Expect(cpu.Value()).To(Equal(int64(3)))
+	})
+
+	It("should not add the delta again when the project-quota already has the recorded spec.hard", func() {
+		// Given
+		request := newRequest()
+		request.Status.Phase = projectv1.QuotaChangeInProgress
+		request.Status.AppliedHard = corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("3")}
+
+		// When
+		result, quota := reconcile(request, corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("3")})
+
+		// Then
+		Expect(result.Status.Phase).To(Equal(projectv1.QuotaChangeApplied))
+		cpu := quota.Spec.Hard[corev1.ResourceLimitsCPU]
+		Expect(cpu.Value()).To(Equal(int64(3)))
+	})
+
+	It("should write the recorded spec.hard when the update of the project-quota was interrupted", func() {
+		// Given
+		request := newRequest()
+		request.Status.Phase = projectv1.QuotaChangeInProgress
+		request.Status.AppliedHard = corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("3")}
+		request.Status.BaseHard = corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2")}
+
+		// When
+		result, quota := reconcile(request, corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2")})
+
+		// Then
+		Expect(result.Status.Phase).To(Equal(projectv1.QuotaChangeApplied))
+		cpu := quota.Spec.Hard[corev1.ResourceLimitsCPU]
+		Expect(cpu.Value()).To(Equal(int64(3)))
+	})
+
+	It("should fail when the project-quota changed since the delta was added to it", func() {
+		// Given
+		request := newRequest()
+		request.Status.Phase = projectv1.QuotaChangeInProgress
+		request.Status.AppliedHard = corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("3")}
+		request.Status.BaseHard = corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2")}
+
+		// When
+		result, quota := reconcile(request, corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("5")})
+
+		// Then
+		Expect(result.Status.Phase).To(Equal(projectv1.QuotaChangeFailed))
+		Expect(result.Status.Message).To(ContainSubstring("changed since the delta was added"))
+		cpu := quota.Spec.Hard[corev1.ResourceLimitsCPU]
+		Expect(cpu.Value()).To(Equal(int64(5)))
+	})
+})
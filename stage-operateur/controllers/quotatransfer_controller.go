@@ -0,0 +1,409 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	projectv1 "project/api/v1"
+	"project/budget"
+	"project/index"
+)
+
+// QuotaTransferReconciler reconciles a QuotaTransfer object
+type QuotaTransferReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// AccountingMode selects the ResourceQuotas counted against the project limits
+	AccountingMode budget.AccountingMode
+	// Names are the project label and the project-quota name
+	Names projectv1.Names
+}
+
+// +kubebuilder:rbac:groups=project.my.domain,resources=quotatransfers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=project.my.domain,resources=quotatransfers/status,verbs=get;update;patch
+
+func (r *QuotaTransferReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	logger := r.Log.WithValues("quotatransfer", req.NamespacedName)
+
+	transfer := &projectv1.QuotaTransfer{}
+	if err := r.Client.Get(ctx, req.NamespacedName, transfer); err != nil {
+		logger.Error(err, "unable to fetch QuotaTransfer")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var err error
+	switch transfer.Status.Phase {
+	case projectv1.QuotaTransferApplied, projectv1.QuotaTransferFailed:
+		return ctrl.Result{}, nil
+	case projectv1.QuotaTransferInProgress:
+		err = r.resumeQuotaTransfer(ctx, transfer)
+	default:
+		err = r.transferQuota(ctx, transfer)
+	}
+	if err != nil {
+		logger.Error(err, "unable to transfer the quota")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.Client.Status().Update(ctx, transfer); err != nil {
+		logger.Error(err, "unable to update QuotaTransfer Status")
+		return ctrl.Result{}, err
+	}
+	logger.Info("quota transfer handled", "phase", transfer.Status.Phase, "message", transfer.Status.Message)
+	if transfer.Status.Phase == projectv1.QuotaTransferInProgress {
+		return ctrl.Result{RequeueAfter: raiseRetryDelay}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// raiseRetryDelay is how long a transfer whose receiving project-quota could
+// not be raised waits before it is retried, so that the caches of the
+// resourceQuota webhook see the giving project-quota lowered.
+const raiseRetryDelay = 5 * time.Second
+
+// transferQuota checks the transfer against the project, records the
+// project-quotas as they are, then lowers the giving one before raising the
+// receiving one, so that the resourceQuota webhook never sees the project
+// over its limits. A raise refused because the webhook does not see the
+// giving project-quota lowered yet leaves the transfer InProgress, to be
+// retried; when the second update fails otherwise the first one is rolled
+// back.
+func (r *QuotaTransferReconciler) transferQuota(ctx context.Context, transfer *projectv1.QuotaTransfer) error {
+	project, message, err := r.projectOfTransfer(ctx, transfer)
+	if err != nil {
+		return err
+	}
+	if message != "" {
+		failQuotaTransfer(&transfer.Status, message)
+		return nil
+	}
+
+	from := corev1.ResourceQuota{}
+	to := corev1.ResourceQuota{}
+	for _, quota := range []struct {
+		namespace string
+		into      *corev1.ResourceQuota
+	}{{transfer.Namespace, &from}, {transfer.Spec.To, &to}} {
+		err := r.Client.Get(ctx, client.ObjectKey{Name: r.Names.QuotaName(), Namespace: quota.namespace}, quota.into)
+		if errors.IsNotFound(err) {
+			failQuotaTransfer(&transfer.Status, "namespace "+quota.namespace+" has no project-quota")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	fromHard, toHard, err := transferAmount(from.Spec.Hard, to.Spec.Hard, transfer.Spec.Amount)
+	if err != nil {
+		failQuotaTransfer(&transfer.Status, err.Error())
+		return nil
+	}
+
+	quotas, err := r.countedProjectQuotas(ctx, project.Name)
+	if err != nil {
+		return err
+	}
+	limits := budget.Allocatable(project.Spec.ProjectLimits, project.Spec.Overcommit)
+	if exceeded := transferOverrun(r.Names, limits, project.Spec.Overcommit, quotas, transfer.Namespace, transfer.Spec.To, fromHard, toHard); len(exceeded) > 0 {
+		failQuotaTransfer(&transfer.Status, "the transfer exceeds the limits of project "+project.Name+": "+budget.Join(exceeded))
+		return nil
+	}
+
+	transfer.Status = projectv1.QuotaTransferStatus{
+		Phase:   projectv1.QuotaTransferInProgress,
+		Message: "moving the amount from namespace " + transfer.Namespace + " to namespace " + transfer.Spec.To,
+		From:    transfer.Namespace,
+		To:      transfer.Spec.To,
+		Before:  &projectv1.QuotaTransferHard{From: from.Spec.Hard, To: to.Spec.Hard},
+		After:   &projectv1.QuotaTransferHard{From: fromHard, To: toHard},
+	}
+	if err := r.Client.Status().Update(ctx, transfer); err != nil {
+		return err
+	}
+
+	from.Spec.Hard = fromHard
+	if err := r.Client.Update(ctx, &from); err != nil {
+		return r.rollbackQuotaTransfer(ctx, transfer, "project-quota of namespace "+transfer.Namespace+" could not be lowered: "+err.Error())
+	}
+	to.Spec.Hard = toHard
+	if err := r.Client.Update(ctx, &to); err != nil {
+		if errors.IsForbidden(err) || errors.IsConflict(err) {
+			transfer.Status.Message = "project-quota of namespace " + transfer.Spec.To + " could not be raised yet, retrying: " + err.Error()
+			return nil
+		}
+		return r.rollbackQuotaTransfer(ctx, transfer, "project-quota of namespace "+transfer.Spec.To+" could not be raised: "+err.Error())
+	}
+
+	transfer.Status.Phase = projectv1.QuotaTransferApplied
+	transfer.Status.Message = "amount moved from namespace " + transfer.Namespace + " to namespace " + transfer.Spec.To
+	return nil
+}
+
+// projectOfTransfer returns the project both namespaces of the transfer
+// belong to, or a message telling why the transfer cannot be made.
+func (r *QuotaTransferReconciler) projectOfTransfer(ctx context.Context, transfer *projectv1.QuotaTransfer) (*projectv1.Project, string, error) {
+	if transfer.Spec.To == transfer.Namespace {
+		return nil, "the amount cannot be transferred to its own namespace", nil
+	}
+	if len(transfer.Spec.Amount) == 0 {
+		return nil, "the amount is empty", nil
+	}
+	for name, amount := range transfer.Spec.Amount {
+		if amount.Sign() <= 0 {
+			return nil, "the amount of " + string(name) + " must be positive", nil
+		}
+	}
+
+	projectName := ""
+	for _, namespaceName := range []string{transfer.Namespace, transfer.Spec.To} {
+		namespace := corev1.Namespace{}
+		err := r.Client.Get(ctx, client.ObjectKey{Name: namespaceName}, &namespace)
+		if errors.IsNotFound(err) {
+			return nil, "namespace " + namespaceName + " does not exist", nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		name, ok := namespace.Labels[r.Names.Label()]
+		if !ok {
+			return nil, "namespace " + namespaceName + " does not belong to a project", nil
+		}
+		if projectName != "" && name != projectName {
+			return nil, "namespaces " + transfer.Namespace + " and " + transfer.Spec.To + " do not belong to the same project", nil
+		}
+		projectName = name
+	}
+
+	project := projectv1.Project{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: projectName}, &project)
+	if errors.IsNotFound(err) {
+		return nil, "project " + projectName + " does not exist", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &project, "", nil
+}
+
+// countedProjectQuotas returns every ResourceQuota counted against the
+// limits of the project, before they are merged per namespace.
+func (r *QuotaTransferReconciler) countedProjectQuotas(ctx context.Context, projectName string) ([]corev1.ResourceQuota, error) {
+	namespaces := corev1.NamespaceList{}
+	if err := r.Client.List(ctx, &namespaces, client.MatchingFields{index.NamespaceProject: projectName}); err != nil {
+		return nil, err
+	}
+	var quotas []corev1.ResourceQuota
+	for _, namespace := range namespaces.Items {
+		namespaceQuotas, err := countedResourceQuotas(ctx, r.Client, r.AccountingMode, r.Names, namespace.Name)
+		if err != nil {
+			return nil, err
+		}
+		quotas = append(quotas, namespaceQuotas...)
+	}
+	return quotas, nil
+}
+
+// resumeQuotaTransfer ends a transfer found InProgress. It is Applied when
+// both project-quotas already have their spec.hard after the transfer. When
+// only the giving one was lowered, the raise of the receiving one is retried
+// once. Otherwise the transfer is rolled back.
+func (r *QuotaTransferReconciler) resumeQuotaTransfer(ctx context.Context, transfer *projectv1.QuotaTransfer) error {
+	if transfer.Status.Before == nil || transfer.Status.After == nil {
+		return r.rollbackQuotaTransfer(ctx, transfer, "the transfer was interrupted")
+	}
+
+	from, to := transferNamespaces(transfer)
+	fromQuota := corev1.ResourceQuota{}
+	toQuota := corev1.ResourceQuota{}
+	for _, quota := range []struct {
+		namespace string
+		into      *corev1.ResourceQuota
+	}{{from, &fromQuota}, {to, &toQuota}} {
+		err := r.Client.Get(ctx, client.ObjectKey{Name: r.Names.QuotaName(), Namespace: quota.namespace}, quota.into)
+		if errors.IsNotFound(err) {
+			return r.rollbackQuotaTransfer(ctx, transfer, "the transfer was interrupted")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !equality.Semantic.DeepEqual(fromQuota.Spec.Hard, transfer.Status.After.From) {
+		return r.rollbackQuotaTransfer(ctx, transfer, "the transfer was interrupted")
+	}
+	if equality.Semantic.DeepEqual(toQuota.Spec.Hard, transfer.Status.Before.To) {
+		toQuota.Spec.Hard = transfer.Status.After.To
+		if err := r.Client.Update(ctx, &toQuota); err != nil {
+			if !errors.IsForbidden(err) && !errors.IsInvalid(err) && !errors.IsConflict(err) {
+				return err
+			}
+			return r.rollbackQuotaTransfer(ctx, transfer, "project-quota of namespace "+to+" could not be raised: "+err.Error())
+		}
+	} else if !equality.Semantic.DeepEqual(toQuota.Spec.Hard, transfer.Status.After.To) {
+		return r.rollbackQuotaTransfer(ctx, transfer, "the transfer was interrupted")
+	}
+
+	transfer.Status.Phase = projectv1.QuotaTransferApplied
+	transfer.Status.Message = "amount moved from namespace " + from + " to namespace " + to
+	return nil
+}
+
+// transferNamespaces returns the namespaces giving and receiving the amount
+// as recorded when the transfer started, so that a later change of spec.to
+// does not redirect a rollback.
+func transferNamespaces(transfer *projectv1.QuotaTransfer) (string, string) {
+	from, to := transfer.Status.From, transfer.Status.To
+	if from == "" {
+		from = transfer.Namespace
+	}
+	if to == "" {
+		to = transfer.Spec.To
+	}
+	return from, to
+}
+
+// rollbackQuotaTransfer puts back the project-quotas of a failed transfer
+// and fails it. A project-quota that was changed by someone else meanwhile
+// is left as is, the message tells so.
+func (r *QuotaTransferReconciler) rollbackQuotaTransfer(ctx context.Context, transfer *projectv1.QuotaTransfer, message string) error {
+	if transfer.Status.Before == nil || transfer.Status.After == nil {
+		failQuotaTransfer(&transfer.Status, message)
+		return nil
+	}
+
+	// The receiving project-quota is put back first, so that the giving one
+	// gets its amount back within the project limits.
+	from, to := transferNamespaces(transfer)
+	for _, quota := range []struct {
+		namespace string
+		before    corev1.ResourceList
+		after     corev1.ResourceList
+	}{
+		{to, transfer.Status.Before.To, transfer.Status.After.To},
+		{from, transfer.Status.Before.From, transfer.Status.After.From},
+	} {
+		restored, err := r.restoreProjectQuota(ctx, quota.namespace, quota.before, quota.after)
+		if err != nil {
+			return err
+		}
+		if !restored {
+			message += "; project-quota of namespace " + quota.namespace + " could not be rolled back"
+		}
+	}
+	failQuotaTransfer(&transfer.Status, message)
+	return nil
+}
+
+// restoreProjectQuota gives back its spec.hard before the transfer to a
+// project-quota the transfer changed. It reports false when the project-quota
+// is neither as before nor as after the transfer, or when the rollback is
+// refused.
+func (r *QuotaTransferReconciler) restoreProjectQuota(ctx context.Context, namespace string, before corev1.ResourceList, after corev1.ResourceList) (bool, error) {
+	quota := corev1.ResourceQuota{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: r.Names.QuotaName(), Namespace: namespace}, &quota)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if equality.Semantic.DeepEqual(quota.Spec.Hard, before) {
+		return true, nil
+	}
+	if !equality.Semantic.DeepEqual(quota.Spec.Hard, after) {
+		return false, nil
+	}
+
+	quota.Spec.Hard = before
+	err = r.Client.Update(ctx, &quota)
+	if errors.IsForbidden(err) || errors.IsInvalid(err) || errors.IsConflict(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// transferAmount returns the spec.hard of both project-quotas once the amount
+// moved. The giving one cannot go negative.
+func transferAmount(fromHard corev1.ResourceList, toHard corev1.ResourceList, amount corev1.ResourceList) (corev1.ResourceList, corev1.ResourceList, error) {
+	negated := corev1.ResourceList{}
+	for name, quantity := range amount {
+		negative := quantity.DeepCopy()
+		negative.Neg()
+		negated[name] = negative
+	}
+	newFromHard, err := applyDelta(fromHard, negated)
+	if err != nil {
+		return nil, nil, fmt.Errorf("the project-quota giving the amount is too small: %v", err)
+	}
+	newToHard, err := applyDelta(toHard, amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newFromHard, newToHard, nil
+}
+
+// transferOverrun returns the project limits that the transfer would push
+// the project over. Limits that were already exceeded do not block it.
+func transferOverrun(names projectv1.Names, limits corev1.ResourceList, overcommit corev1.ResourceList, quotas []corev1.ResourceQuota, from string, to string, fromHard corev1.ResourceList, toHard corev1.ResourceList) []corev1.ResourceName {
+	transferred := make([]corev1.ResourceQuota, 0, len(quotas))
+	for _, quota := range quotas {
+		if quota.Name == names.QuotaName() && quota.Namespace == from {
+			quota.Spec.Hard = fromHard
+		}
+		if quota.Name == names.QuotaName() && quota.Namespace == to {
+			quota.Spec.Hard = toHard
+		}
+		transferred = append(transferred, quota)
+	}
+
+	exceeded := map[corev1.ResourceName]bool{}
+	for _, name := range budget.Exceeded(limits, budget.Overcommitted(budget.PerNamespace(quotas), overcommit)) {
+		exceeded[name] = true
+	}
+	var overrun []corev1.ResourceName
+	for _, name := range budget.Exceeded(limits, budget.Overcommitted(budget.PerNamespace(transferred), overcommit)) {
+		if !exceeded[name] {
+			overrun = append(overrun, name)
+		}
+	}
+	return overrun
+}
+
+func failQuotaTransfer(status *projectv1.QuotaTransferStatus, message string) {
+	status.Phase = projectv1.QuotaTransferFailed
+	status.Message = message
+}
+
+func (r *QuotaTransferReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&projectv1.QuotaTransfer{}).
+		Complete(r)
+}
@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"context"
+
+	projectv1 "project/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// raiseRefusingClient refuses to update the ResourceQuotas of a namespace, as
+// the resourceQuota webhook does while its cache still has the giving
+// project-quota of a transfer as it was.
+type raiseRefusingClient struct {
+	client.Client
+	namespace string
+}
+
+func (c raiseRefusingClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if quota, ok := obj.(*corev1.ResourceQuota); ok && quota.Namespace == c.namespace {
+		return errors.NewForbidden(schema.GroupResource{Resource: "resourcequotas"}, quota.Name, errors.NewBadRequest("project limits exceeded"))
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+var _ = Describe("transferAmount", func() {
+	fromHard := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("4"), corev1.ResourceLimitsMemory: resource.MustParse("8Gi")}
+	toHard := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1")}
+
+	It("should move the amount from one spec.hard to the other", func() {
+		// Given
+		amount := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1500m"), corev1.ResourceLimitsMemory: resource.MustParse("2Gi")}
+
+		// When
+		newFromHard, newToHard, err := transferAmount(fromHard, toHard, amount)
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		fromCpu := newFromHard[corev1.ResourceLimitsCPU]
+		toCpu := newToHard[corev1.ResourceLimitsCPU]
+		toMemory := newToHard[corev1.ResourceLimitsMemory]
+		Expect(fromCpu.MilliValue()).To(Equal(int64(2500)))
+		Expect(toCpu.MilliValue()).To(Equal(int64(2500)))
+		Expect(toMemory.Cmp(resource.MustParse("2Gi"))).To(Equal(0))
+		Expect(toHard).NotTo(HaveKey(corev1.ResourceLimitsMemory))
+	})
+
+	It("should refuse to take more than the giving spec.hard has", func() {
+		// Given
+		amount := corev1.ResourceList{corev1.ResourcePods: resource.MustParse("1")}
+
+		// When
+		_, _, err := transferAmount(fromHard, toHard, amount)
+
+		// Then
+		Expect(err).To(MatchError("the project-quota giving the amount is too small: pods would be negative"))
+	})
+})
+
+var _ = Describe("transferOverrun", func() {
+	newQuota := func(namespace string, cpu string) corev1.ResourceQuota {
+		return corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "project-quota", Namespace: namespace},
+			Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse(cpu)}},
+		}
+	}
+	limits := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("4")}
+	quotas := []corev1.ResourceQuota{newQuota("test1", "3"), newQuota("test2", "1")}
+
+	It("should accept a transfer keeping the project total", func() {
+		// When
+		overrun := transferOverrun(projectv1.Names{}, limits, nil, quotas, "test1", "test2",
+			corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1")},
+			corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("3")})
+
+		// Then
+		Expect(overrun).To(BeEmpty())
+	})
+
+	It("should report the limits a transfer would push the project over", func() {
+		// When
+		overrun := transferOverrun(projectv1.Names{}, limits, nil, quotas, "test1", "test2",
+			corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("3")},
+			corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2")})
+
+		// Then
+		Expect(overrun).To(Equal([]corev1.ResourceName{corev1.ResourceLimitsCPU}))
+	})
+})
+
+var _ = Describe("QuotaTransferReconciler", func() {
+	key := types.NamespacedName{Name: "give-cpu", Namespace: "test1"}
+	cpu := func(value string) corev1.ResourceList {
+		return corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse(value)}
+	}
+
+	// reconcile runs the reconciler once on a transfer found InProgress, with
+	// the project-quotas of the namespaces test1, test2 and test3 as given,
+	// and returns the transfer and the spec.hard of the project-quotas as
+	// they are left.
+	reconcile := func(transfer *projectv1.QuotaTransfer, hards map[string]corev1.ResourceList) (projectv1.QuotaTransfer, map[string]corev1.ResourceList) {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(projectv1.AddToScheme(scheme)).To(Succeed())
+		objects := []runtime.Object{transfer, &projectv1.Project{ObjectMeta: metav1.ObjectMeta{Name: "project-1"}}}
+		for namespace, hard := range hards {
+			objects = append(objects,
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: map[string]string{projectv1.ProjectLabel: "project-1"}}},
+				&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: projectv1.ProjectQuotaName, Namespace: namespace}, Spec: corev1.ResourceQuotaSpec{Hard: hard}})
+		}
+		c := fake.NewFakeClientWithScheme(scheme, objects...)
+		reconciler := &QuotaTransferReconciler{Client: c, Log: ctrl.Log, Scheme: scheme}
+
+		_, err := reconciler.Reconcile(ctrl.Request{NamespacedName: key})
+
+		Expect(err).NotTo(HaveOccurred())
+		result := projectv1.QuotaTransfer{}
+		Expect(c.Get(context.Background(), key, &result)).To(Succeed())
+		left := map[string]corev1.ResourceList{}
+		for namespace := range hards {
+			quota := corev1.ResourceQuota{}
+			Expect(c.Get(context.Background(), client.ObjectKey{Name: projectv1.ProjectQuotaName, Namespace: namespace}, &quota)).To(Succeed())
+			left[namespace] = quota.Spec.Hard
+		}
+		return result, left
+	}
+
+	newTransfer := func() *projectv1.QuotaTransfer {
+		return &projectv1.QuotaTransfer{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       projectv1.QuotaTransferSpec{To: "test2", Amount: cpu("1")},
+			Status: projectv1.QuotaTransferStatus{
+				Phase:  projectv1.QuotaTransferInProgress,
+				From:   "test1",
+				To:     "test2",
+				Before: &projectv1.QuotaTransferHard{From: cpu("3"), To: cpu("1")},
+				After:  &projectv1.QuotaTransferHard{From: cpu("2"), To: cpu("2")},
+			},
+		}
+	}
+
+	It("should mark Applied an interrupted transfer whose project-quotas are as after it", func() {
+		// Given
+		transfer := newTransfer()
+
+		// When
+		result, hards := reconcile(transfer, map[string]corev1.ResourceList{"test1": cpu("2"), "test2": cpu("2")})
+
+		// Then
+		Expect(result.Status.Phase).To(Equal(projectv1.QuotaTransferApplied))
+		limit := hards["test1"][corev1.ResourceLimitsCPU]
+		Expect(limit.Value()).To(Equal(int64(2)))
+		limit = hards["test2"][corev1.ResourceLimitsCPU]
+		Expect(limit.Value()).To(Equal(int64(2)))
+	})
+
+	It("should roll back an interrupted transfer in the namespaces recorded in its status", func() {
+		// Given
+		transfer := newTransfer()
+		transfer.Spec.To = "test3"
+
+		// When
+		result, hards := reconcile(transfer, map[string]corev1.ResourceList{"test1": cpu("3"), "test2": cpu("1"), "test3": cpu("2")})
+
+		// Then
+		Expect(result.Status.Phase).To(Equal(projectv1.QuotaTransferFailed))
+		Expect(result.Status.Message).To(Equal("the transfer was interrupted"))
+		limit := hards["test1"][corev1.ResourceLimitsCPU]
+		Expect(limit.Value()).To(Equal(int64(3)))
+		limit = hards["test2"][corev1.ResourceLimitsCPU]
+		Expect(limit.Value()).To(Equal(int64(1)))
+		limit = hards["test3"][corev1.ResourceLimitsCPU]
+		Expect(limit.Value()).To(Equal(int64(2)))
+	})
+
+	It("should raise the receiving project-quota of an interrupted transfer whose giving one was lowered", func() {
+		// Given
+		transfer := newTransfer()
+
+		// When
+		result, hards := reconcile(transfer, map[string]corev1.ResourceList{"test1": cpu("2"), "test2": cpu("1")})
+
+		// Then
+		Expect(result.Status.Phase).To(Equal(projectv1.QuotaTransferApplied))
+		limit := hards["test1"][corev1.ResourceLimitsCPU]
+		Expect(limit.Value()).To(Equal(int64(2)))
+		limit = hards["test2"][corev1.ResourceLimitsCPU]
+		Expect(limit.Value()).To(Equal(int64(2)))
+	})
+
+	It("should keep a transfer InProgress and requeue it when the raise is refused", func() {
+		// Given
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(projectv1.AddToScheme(scheme)).To(Succeed())
+		objects := []runtime.Object{&projectv1.Project{ObjectMeta: metav1.ObjectMeta{Name: "project-1"}}}
+		for namespace, hard := range map[string]corev1.ResourceList{"test1": cpu("3"), "test2": cpu("1")} {
+			objects = append(objects,
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: map[string]string{projectv1.ProjectLabel: "project-1"}}},
+				&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: projectv1.ProjectQuotaName, Namespace: namespace}, Spec: corev1.ResourceQuotaSpec{Hard: hard}})
+		}
+		transfer := &projectv1.QuotaTransfer{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       projectv1.QuotaTransferSpec{To: "test2", Amount: cpu("1")},
+		}
+		c := raiseRefusingClient{fake.NewFakeClientWithScheme(scheme, append(objects, transfer)...), "test2"}
+		reconciler := &QuotaTransferReconciler{Client: c, Log: ctrl.Log, Scheme: scheme}
+
+		// When
+		result, err := reconciler.Reconcile(ctrl.Request{NamespacedName: key})
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(raiseRetryDelay))
+		Expect(c.Get(context.Background(), key, transfer)).To(Succeed())
+		Expect(transfer.Status.Phase).To(Equal(projectv1.QuotaTransferInProgress))
+		Expect(transfer.Status.Message).To(ContainSubstring("could not be raised yet, retrying"))
+		quota := corev1.ResourceQuota{}
+		Expect(c.Get(context.Background(), client.ObjectKey{Name: projectv1.ProjectQuotaName, Namespace: "test1"}, &quota)).To(Succeed())
+		limit := quota.Spec.Hard[corev1.ResourceLimitsCPU]
+		Expect(limit.Value()).To(Equal(int64(2)))
+	})
+})
@@ -0,0 +1,220 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	projectv1 "project/api/v1"
+)
+
+// +kubebuilder:rbac:groups=core,resources=limitranges;configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
+
+// Binding the admin, edit and view ClusterRoles, and no other, is granted by
+// config/rbac/bind_role.yaml.
+
+// templateObjects returns the objects of the project's namespace template as
+// they must exist in the namespace.
+func templateObjects(project *projectv1.Project, namespace string) ([]*unstructured.Unstructured, error) {
+	if project.Spec.NamespaceTemplate == nil {
+		return nil, nil
+	}
+
+	objects := make([]*unstructured.Unstructured, 0, len(project.Spec.NamespaceTemplate.Objects))
+	for i, raw := range project.Spec.NamespaceTemplate.Objects {
+		object := &unstructured.Unstructured{}
+		if err := object.UnmarshalJSON(raw.Raw); err != nil {
+			return nil, fmt.Errorf("namespaceTemplate object %d: %v", i, err)
+		}
+		if object.GetName() == "" {
+			return nil, fmt.Errorf("namespaceTemplate object %d has no name", i)
+		}
+		if !templateKind(object.GroupVersionKind()) {
+			return nil, fmt.Errorf("namespaceTemplate object %d: kind %s of %s is not allowed", i, object.GetKind(), object.GetAPIVersion())
+		}
+		object.SetNamespace(namespace)
+		labels := object.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[projectv1.NamespaceTemplateLabel] = project.Name
+		object.SetLabels(labels)
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// syncTemplate creates the template objects missing from the namespace,
+// restores the ones that drifted and deletes the ones no longer in the
+// template or stamped by another project. A template that cannot be stamped
+// still has the objects of another project deleted.
+func (r *NamespaceReconciler) syncTemplate(ctx context.Context, project *projectv1.Project, namespace string) error {
+	objects, err := templateObjects(project, namespace)
+	if err != nil {
+		if deleteErr := r.deleteTemplateObjects(ctx, namespace, project.Name, nil); deleteErr != nil {
+			return deleteErr
+		}
+		return err
+	}
+
+	wanted := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		wanted[templateKey(object.GroupVersionKind(), object.GetName())] = true
+
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(object.GroupVersionKind())
+		err := r.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: object.GetName()}, existing)
+		if errors.IsNotFound(err) {
+			if err := r.Client.Create(ctx, object); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if restoreTemplateObject(existing, object) {
+			if err := r.Client.Update(ctx, existing); err != nil {
+				return err
+			}
+		}
+	}
+
+	return r.deleteTemplateObjects(ctx, namespace, project.Name, wanted)
+}
+
+// deleteTemplateObjects deletes the template objects of the namespace stamped
+// by another project than projectName, and the ones of projectName that are
+// not wanted. A nil wanted keeps every object of projectName; an empty
+// projectName deletes them all, e.g. when the namespace left its project.
+func (r *NamespaceReconciler) deleteTemplateObjects(ctx context.Context, namespace string, projectName string, wanted map[string]bool) error {
+	for _, gvk := range projectv1.NamespaceTemplateKinds() {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := r.Client.List(ctx, list, client.InNamespace(namespace), client.HasLabels{projectv1.NamespaceTemplateLabel}); err != nil {
+			return err
+		}
+		for i := range list.Items {
+			if list.Items[i].GetLabels()[projectv1.NamespaceTemplateLabel] == projectName &&
+				(wanted == nil || wanted[templateKey(gvk, list.Items[i].GetName())]) {
+				continue
+			}
+			if err := r.Client.Delete(ctx, &list.Items[i]); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func templateKind(gvk schema.GroupVersionKind) bool {
+	for _, kind := range projectv1.NamespaceTemplateKinds() {
+		if kind == gvk {
+			return true
+		}
+	}
+	return false
+}
+
+func templateKey(gvk schema.GroupVersionKind, name string) string {
+	return gvk.GroupKind().String() + "/" + name
+}
+
+// restoreTemplateObject copies the fields of the template object over the
+// existing one when they drifted, and reports whether it did. Fields the
+// template does not set, such as the ones defaulted by the API server, are
+// left alone.
+func restoreTemplateObject(existing *unstructured.Unstructured, object *unstructured.Unstructured) bool {
+	changed := false
+	for field, value := range object.Object {
+		switch field {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			labels := existing.GetLabels()
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			for key, label := range object.GetLabels() {
+				if labels[key] != label {
+					labels[key] = label
+					changed = true
+				}
+			}
+			existing.SetLabels(labels)
+			annotations := existing.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			for key, annotation := range object.GetAnnotations() {
+				if annotations[key] != annotation {
+					annotations[key] = annotation
+					changed = true
+				}
+			}
+			if len(annotations) > 0 {
+				existing.SetAnnotations(annotations)
+			}
+		default:
+			if !containsFields(existing.Object[field], value) {
+				existing.Object[field] = value
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// containsFields reports whether every field set in desired has the same
+// value in actual.
+func containsFields(actual interface{}, desired interface{}) bool {
+	switch desired := desired.(type) {
+	case map[string]interface{}:
+		actual, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range desired {
+			if !containsFields(actual[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		actual, ok := actual.([]interface{})
+		if !ok || len(actual) != len(desired) {
+			return false
+		}
+		for i := range desired {
+			if !containsFields(actual[i], desired[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(actual, desired)
+	}
+}
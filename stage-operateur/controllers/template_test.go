@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+
+	projectv1 "project/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("templateObjects", func() {
+	It("should stamp the template objects into the namespace", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{Name: "project-test1"},
+			Spec: projectv1.ProjectSpec{
+				NamespaceTemplate: &projectv1.NamespaceTemplate{Objects: []runtime.RawExtension{
+					{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"team","labels":{"team":"a"}},"data":{"owner":"a"}}`)},
+				}},
+			},
+		}
+
+		// When
+		objects, err := templateObjects(&project, "test1")
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects).To(HaveLen(1))
+		Expect(objects[0].GetNamespace()).To(Equal("test1"))
+		Expect(objects[0].GetLabels()).To(Equal(map[string]string{"team": "a", projectv1.NamespaceTemplateLabel: "project-test1"}))
+	})
+
+	It("should refuse the kinds a namespace template may not hold", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: metav1.ObjectMeta{Name: "project-test1"},
+			Spec: projectv1.ProjectSpec{
+				NamespaceTemplate: &projectv1.NamespaceTemplate{Objects: []runtime.RawExtension{
+					{Raw: []byte(`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"Role","metadata":{"name":"everything"}}`)},
+				}},
+			},
+		}
+
+		// When
+		_, err := templateObjects(&project, "test1")
+
+		// Then
+		Expect(err).To(MatchError("namespaceTemplate object 0: kind Role of rbac.authorization.k8s.io/v1 is not allowed"))
+	})
+
+	It("should return nothing without template", func() {
+		// When
+		objects, err := templateObjects(&projectv1.Project{}, "test1")
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects).To(BeEmpty())
+	})
+})
+
+var _ = Describe("restoreTemplateObject", func() {
+	newObject := func(data map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": "team", "namespace": "test1"},
+			"data":       data,
+		}}
+	}
+
+	It("should leave an object with extra fields alone", func() {
+		// Given
+		existing := newObject(map[string]interface{}{"owner": "a", "added": "by the server"})
+		object := newObject(map[string]interface{}{"owner": "a"})
+
+		// Then
+		Expect(restoreTemplateObject(existing, object)).To(BeFalse())
+	})
+
+	It("should restore an edited field", func() {
+		// Given
+		existing := newObject(map[string]interface{}{"owner": "b"})
+		object := newObject(map[string]interface{}{"owner": "a"})
+
+		// When
+		changed := restoreTemplateObject(existing, object)
+
+		// Then
+		Expect(changed).To(BeTrue())
+		Expect(existing.Object["data"]).To(Equal(map[string]interface{}{"owner": "a"}))
+	})
+})
+
+var _ = Describe("deleteTemplateObjects", func() {
+	templateRoleBinding := func(name string, projectName string) *rbacv1.RoleBinding {
+		return &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test1",
+				Labels:    map[string]string{projectv1.NamespaceTemplateLabel: projectName},
+			},
+			RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "edit"},
+		}
+	}
+
+	newReconciler := func(objects ...runtime.Object) *NamespaceReconciler {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(projectv1.AddToScheme(scheme)).To(Succeed())
+		return &NamespaceReconciler{Client: fake.NewFakeClientWithScheme(scheme, objects...), Log: ctrl.Log, Scheme: scheme}
+	}
+
+	It("should delete the template objects of the project the namespace left", func() {
+		// Given
+		reconciler := newReconciler(templateRoleBinding("old-team", "project-1"), templateRoleBinding("team", "project-2"))
+
+		// When
+		err := reconciler.deleteTemplateObjects(context.Background(), "test1", "project-2", nil)
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		roleBindings := &rbacv1.RoleBindingList{}
+		Expect(reconciler.Client.List(context.Background(), roleBindings, client.InNamespace("test1"))).To(Succeed())
+		Expect(roleBindings.Items).To(HaveLen(1))
+		Expect(roleBindings.Items[0].Name).To(Equal("team"))
+	})
+
+	It("should delete every template object of a namespace without project", func() {
+		// Given
+		reconciler := newReconciler(templateRoleBinding("old-team", "project-1"), templateRoleBinding("team", "project-2"))
+
+		// When
+		err := reconciler.deleteTemplateObjects(context.Background(), "test1", "", nil)
+
+		// Then
+		Expect(err).NotTo(HaveOccurred())
+		roleBindings := &rbacv1.RoleBindingList{}
+		Expect(reconciler.Client.List(context.Background(), roleBindings, client.InNamespace("test1"))).To(Succeed())
+		Expect(roleBindings.Items).To(BeEmpty())
+	})
+})
@@ -0,0 +1,67 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package index defines the field indexes of the manager's cache that the
+// reconcilers and the webhooks list with, so that the namespaces and the
+// children of a project are found without scanning them all.
+package index
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	projectv1 "project/api/v1"
+)
+
+const (
+	// NamespaceProject indexes the namespaces by their project label
+	NamespaceProject = "metadata.labels.project"
+	// ProjectParent indexes the projects by their spec.parent
+	ProjectParent = "spec.parent"
+)
+
+// Setup registers the field indexes, the namespaces being indexed by the
+// project label of names. It must be called once, before the reconcilers and
+// the webhooks are set up.
+func Setup(indexer client.FieldIndexer, names projectv1.Names) error {
+	if err := indexer.IndexField(&corev1.Namespace{}, NamespaceProject, namespaceProject(names)); err != nil {
+		return err
+	}
+	return indexer.IndexField(&projectv1.Project{}, ProjectParent, projectParent)
+}
+
+func namespaceProject(names projectv1.Names) client.IndexerFunc {
+	return func(object runtime.Object) []string {
+		namespace, ok := object.(*corev1.Namespace)
+		if !ok {
+			return nil
+		}
+		projectName, ok := namespace.Labels[names.Label()]
+		if !ok {
+			return nil
+		}
+		return []string{projectName}
+	}
+}
+
+func projectParent(object runtime.Object) []string {
+	project, ok := object.(*projectv1.Project)
+	if !ok || project.Spec.Parent == "" {
+		return nil
+	}
+	return []string{project.Spec.Parent}
+}
@@ -0,0 +1,63 @@
+package index
+
+import (
+	projectv1 "project/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("namespaceProject", func() {
+	It("should index a namespace by its project", func() {
+		// Given
+		namespace := corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "namespace-1", Labels: map[string]string{"project": "project-1"}}}
+
+		// When
+		values := namespaceProject(projectv1.Names{})(&namespace)
+
+		// Then
+		Expect(values).To(Equal([]string{"project-1"}))
+	})
+
+	It("should index a namespace by the configured project label", func() {
+		// Given
+		namespace := corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "namespace-1", Labels: map[string]string{"project": "project-1", "team": "team-1"}}}
+
+		// When
+		values := namespaceProject(projectv1.Names{ProjectLabel: "team"})(&namespace)
+
+		// Then
+		Expect(values).To(Equal([]string{"team-1"}))
+	})
+
+	It("should not index a namespace without project", func() {
+		// When
+		values := namespaceProject(projectv1.Names{})(&corev1.Namespace{})
+
+		// Then
+		Expect(values).To(BeEmpty())
+	})
+})
+
+var _ = Describe("projectParent", func() {
+	It("should index a project by its parent", func() {
+		// Given
+		project := projectv1.Project{Spec: projectv1.ProjectSpec{Parent: "parent"}}
+
+		// When
+		values := projectParent(&project)
+
+		// Then
+		Expect(values).To(Equal([]string{"parent"}))
+	})
+
+	It("should not index a root project", func() {
+		// When
+		values := projectParent(&projectv1.Project{})
+
+		// Then
+		Expect(values).To(BeEmpty())
+	})
+})
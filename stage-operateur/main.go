@@ -20,6 +20,7 @@ import (
 	"flag"
 	"os"
 	webhook2 "project/webhook"
+	"strings"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -32,8 +33,12 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 
+	configv1alpha1 "project/api/config/v1alpha1"
 	projectv1 "project/api/v1"
+	"project/budget"
 	"project/controllers"
+	"project/index"
+	"project/metrics"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -53,40 +58,124 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var allowLimitShrink bool
+	var namespaceLabelUsers string
+	var namespaceLabelGroups string
+	var accountingModeValue string
+	var configFile string
+	var operatorUsername string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&allowLimitShrink, "allow-limit-shrink", false,
+		"Allow project limits to be lowered below what the namespaces' project-quotas already allocate. "+
+			"The project is then reported OverCommitted instead of the update being denied.")
+	flag.StringVar(&namespaceLabelUsers, "namespace-label-users", "",
+		"Comma-separated users allowed to create a namespace with the 'project' label, or to add, change or remove it. "+
+			"When neither users nor groups are set, anyone who can create or update the namespace may.")
+	flag.StringVar(&namespaceLabelGroups, "namespace-label-groups", "",
+		"Comma-separated groups allowed to create a namespace with the 'project' label, or to add, change or remove it.")
+	flag.StringVar(&accountingModeValue, "accounting-mode", string(budget.ManagedQuota),
+		"ResourceQuotas counted against the project limits: "+
+			"ManagedQuota counts the project-quota of each namespace, "+
+			"AllQuotas counts every ResourceQuota keeping the tightest per namespace, "+
+			"Exclusive counts the project-quota and refuses any other ResourceQuota in project namespaces.")
+	flag.StringVar(&configFile, "config", "",
+		"Path to an OperatorConfig file, e.g. a mounted ConfigMap, setting the project label, the project-quota name, "+
+			"the webhook port, the leader election ID and the accounting policies. Flags set on the command line override it.")
+	flag.StringVar(&operatorUsername, "operator-username", "",
+		"User the operator runs as, e.g. system:serviceaccount:<namespace>:<name>. "+
+			"It may create namespaces with the 'project' label for NamespaceRequests whatever the namespace label users and groups.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
+	operatorConfig, err := loadOperatorConfig(configFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load the operator configuration")
+		os.Exit(1)
+	}
+	accountingMode, err := budget.ParseAccountingMode(operatorConfig.Accounting.Mode)
+	if err != nil {
+		setupLog.Error(err, "invalid operator configuration", "field", "accounting.mode")
+		os.Exit(1)
+	}
+	names := projectv1.Names{ProjectLabel: operatorConfig.ProjectLabel, ProjectQuotaName: operatorConfig.ProjectQuotaName}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:             scheme,
 		MetricsBindAddress: metricsAddr,
-		Port:               9443,
+		Port:               operatorConfig.WebhookPort,
 		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "f4bd82be.my.domain",
+		LeaderElectionID:   operatorConfig.LeaderElectionID,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	if err = index.Setup(mgr.GetFieldIndexer(), names); err != nil {
+		setupLog.Error(err, "unable to set up field indexes")
+		os.Exit(1)
+	}
 	if err = (&controllers.ProjectReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Project"),
-		Scheme: mgr.GetScheme(),
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("Project"),
+		Scheme:         mgr.GetScheme(),
+		AccountingMode: accountingMode,
+		Names:          names,
+		Recorder:       mgr.GetEventRecorderFor("project-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Project")
 		os.Exit(1)
 	}
 	if err = (&controllers.NamespaceReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("Namespace"),
+		Scheme:         mgr.GetScheme(),
+		AccountingMode: accountingMode,
+		Names:          names,
+		Recorder:       mgr.GetEventRecorderFor("namespace-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Namespace")
+		os.Exit(1)
+	}
+	if err = (&controllers.NamespaceRequestReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("NamespaceRequest"),
+		Scheme:         mgr.GetScheme(),
+		AccountingMode: accountingMode,
+		Names:          names,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "NamespaceRequest")
+		os.Exit(1)
+	}
+	if err = (&controllers.QuotaChangeRequestReconciler{
 		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Namespace"),
+		Log:    ctrl.Log.WithName("controllers").WithName("QuotaChangeRequest"),
 		Scheme: mgr.GetScheme(),
+		Names:  names,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Namespace")
+		setupLog.Error(err, "unable to create controller", "controller", "QuotaChangeRequest")
+		os.Exit(1)
+	}
+	if err = (&controllers.QuotaTransferReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("QuotaTransfer"),
+		Scheme:         mgr.GetScheme(),
+		AccountingMode: accountingMode,
+		Names:          names,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "QuotaTransfer")
+		os.Exit(1)
+	}
+	if err = (&controllers.ClusterBudgetReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("ClusterBudget"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterBudget")
 		os.Exit(1)
 	}
 	// +kubebuilder:scaffold:builder
@@ -96,7 +185,22 @@ func main() {
 	hookServer := mgr.GetWebhookServer()
 
 	logf.Log.Info("registering webhooks to the webhook server")
-	hookServer.Register("/validate-v1-resourcequota", &webhook.Admission{Handler: &webhook2.ResourceQuotaValidator{Client: mgr.GetClient()}})
+	hookServer.Register("/validate-v1-resourcequota", &webhook.Admission{Handler: metrics.InstrumentAdmission("resourcequota", &webhook2.ResourceQuotaValidator{
+		Client:         mgr.GetClient(),
+		AccountingMode: accountingMode,
+		Names:          names,
+		Recorder:       mgr.GetEventRecorderFor("resourcequota-webhook"),
+	})})
+	hookServer.Register("/validate-v1-namespace", &webhook.Admission{Handler: metrics.InstrumentAdmission("namespace", &webhook2.NamespaceValidator{
+		Client:           mgr.GetClient(),
+		AuthorizedUsers:  operatorConfig.NamespaceLabel.Users,
+		AuthorizedGroups: operatorConfig.NamespaceLabel.Groups,
+		OperatorUsername: operatorUsername,
+		AccountingMode:   accountingMode,
+		Names:            names,
+	})})
+	hookServer.Register("/validate-v1-project", &webhook.Admission{Handler: metrics.InstrumentAdmission("project", &webhook2.ProjectValidator{Client: mgr.GetClient(), AllowShrinkBelowAllocation: operatorConfig.Accounting.AllowLimitShrink, AccountingMode: accountingMode, Names: names})})
+	hookServer.Register("/mutate-v1-quotachangerequest", &webhook.Admission{Handler: metrics.InstrumentAdmission("quotachangerequest", &webhook2.QuotaChangeRequestAdmission{Client: mgr.GetClient(), Names: names})})
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -104,3 +208,41 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// loadOperatorConfig reads the configuration file, when one is given, then
+// applies over it the flags set on the command line.
+func loadOperatorConfig(path string) (configv1alpha1.OperatorConfig, error) {
+	config := configv1alpha1.Default()
+	if path != "" {
+		loaded, err := configv1alpha1.Load(path)
+		if err != nil {
+			return config, err
+		}
+		config = loaded
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "accounting-mode":
+			config.Accounting.Mode = f.Value.String()
+		case "allow-limit-shrink":
+			config.Accounting.AllowLimitShrink = f.Value.String() == "true"
+		case "namespace-label-users":
+			config.NamespaceLabel.Users = splitList(f.Value.String())
+		case "namespace-label-groups":
+			config.NamespaceLabel.Groups = splitList(f.Value.String())
+		}
+	})
+	return config, config.Validate()
+}
+
+// splitList splits a comma-separated flag value, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
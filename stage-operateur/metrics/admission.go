@@ -0,0 +1,83 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// InstrumentAdmission counts the decisions of the handler in
+// AdmissionDecisions, under the given webhook name.
+func InstrumentAdmission(webhook string, handler admission.Handler) admission.Handler {
+	return &instrumentedHandler{webhook: webhook, handler: handler}
+}
+
+type instrumentedHandler struct {
+	webhook string
+	handler admission.Handler
+}
+
+func (h *instrumentedHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	response := h.handler.Handle(ctx, req)
+	decision, reason := admissionDecision(response)
+	AdmissionDecisions.WithLabelValues(h.webhook, strings.ToLower(string(req.Operation)), decision, reason).Inc()
+	return response
+}
+
+// InjectDecoder passes the decoder on to the instrumented handler.
+func (h *instrumentedHandler) InjectDecoder(d *admission.Decoder) error {
+	if injector, ok := h.handler.(admission.DecoderInjector); ok {
+		return injector.InjectDecoder(d)
+	}
+	return nil
+}
+
+// admissionDecision returns the decision of the response and its reason. The
+// reason of a denial is the fixed code the webhooks set in Result.Reason,
+// never the message, which names projects, namespaces and users and would
+// make too many series; a denial without code is counted as Forbidden and an
+// error by its status code.
+func admissionDecision(response admission.Response) (string, string) {
+	if response.Allowed {
+		return "allowed", "Allowed"
+	}
+	code := int32(http.StatusForbidden)
+	if response.Result != nil && response.Result.Code != 0 {
+		code = response.Result.Code
+	}
+	switch code {
+	case http.StatusForbidden:
+		if response.Result != nil && isReasonCode(string(response.Result.Reason)) {
+			return "denied", string(response.Result.Reason)
+		}
+		return "denied", "Forbidden"
+	case http.StatusBadRequest:
+		return "errored", "BadRequest"
+	default:
+		return "errored", "InternalError"
+	}
+}
+
+// isReasonCode reports whether the reason is a code such as LimitsExceeded
+// rather than a sentence.
+func isReasonCode(reason string) bool {
+	return reason != "" && !strings.ContainsAny(reason, " :")
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("admissionDecision", func() {
+	It("should classify the responses without their message", func() {
+		Expect(admissionDecisionOf(admission.Allowed("project limits are valid"))).To(Equal([]string{"allowed", "Allowed"}))
+		Expect(admissionDecisionOf(deniedFor("ProjectNotFound", "parent project a does not exist"))).To(Equal([]string{"denied", "ProjectNotFound"}))
+		Expect(admissionDecisionOf(admission.Denied("parent project a does not exist"))).To(Equal([]string{"denied", "Forbidden"}))
+		Expect(admissionDecisionOf(admission.Errored(http.StatusBadRequest, errors.New("bad")))).To(Equal([]string{"errored", "BadRequest"}))
+		Expect(admissionDecisionOf(admission.Errored(http.StatusInternalServerError, errors.New("boom")))).To(Equal([]string{"errored", "InternalError"}))
+	})
+})
+
+var _ = Describe("InstrumentAdmission", func() {
+	It("should count the decisions by webhook, operation and reason", func() {
+		// Given
+		handler := InstrumentAdmission("test", admission.HandlerFunc(func(context.Context, admission.Request) admission.Response {
+			return deniedFor("LimitsExceeded", "adding the namespace to project project-1 exceeds its limits: limits.cpu")
+		}))
+		req := admission.Request{AdmissionRequest: v1beta1.AdmissionRequest{Operation: v1beta1.Update}}
+
+		// When
+		response := handler.Handle(context.Background(), req)
+
+		// Then
+		Expect(response.Allowed).To(BeFalse())
+		Expect(testutil.ToFloat64(AdmissionDecisions.WithLabelValues("test", "update", "denied", "LimitsExceeded"))).To(Equal(1.0))
+	})
+})
+
+// deniedFor is a denial with a reason code, as the webhooks return them.
+func deniedFor(reason metav1.StatusReason, message string) admission.Response {
+	response := admission.Denied(message)
+	response.Result.Reason = reason
+	response.Result.Message = message
+	return response
+}
+
+func admissionDecisionOf(response admission.Response) []string {
+	decision, reason := admissionDecision(response)
+	return []string{decision, reason}
+}
@@ -0,0 +1,165 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics publishes the project budgets and the admission decisions
+// on the metrics endpoint of the manager.
+//
+// Reconcile errors are already counted by controller-runtime, per
+// controller, in controller_runtime_reconcile_errors_total.
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	projectv1 "project/api/v1"
+)
+
+var (
+	// ProjectLimit is the limit of a project for a resource
+	ProjectLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "project_limit",
+		Help: "Limit of the project for the resource",
+	}, []string{"project", "resource"})
+
+	// ProjectAllocated is the part of a project limit granted by resourceQuotas
+	ProjectAllocated = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "project_allocated",
+		Help: "Sum of the spec.hard of the resourceQuotas of the project for the resource",
+	}, []string{"project", "resource"})
+
+	// ProjectUsed is the part of a project limit in use
+	ProjectUsed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "project_used",
+		Help: "Sum of the status.used of the resourceQuotas of the project for the resource",
+	}, []string{"project", "resource"})
+
+	// ClusterBudgetCapacity is the capacity of a ClusterBudget for a resource
+	ClusterBudgetCapacity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cluster_budget_capacity",
+		Help: "Capacity of the ClusterBudget for the resource",
+	}, []string{"budget", "resource"})
+
+	// ClusterBudgetCommitted is the part of a ClusterBudget capacity committed
+	// by the projects
+	ClusterBudgetCommitted = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cluster_budget_committed",
+		Help: "Sum of the projectLimits of the projects without parent for the resource",
+	}, []string{"budget", "resource"})
+
+	// AdmissionDecisions counts the decisions of the webhooks
+	AdmissionDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "project_webhook_decisions_total",
+		Help: "Number of admission decisions of the webhooks",
+	}, []string{"webhook", "operation", "decision", "reason"})
+)
+
+// resources remembers the resources published for every project and every
+// cluster budget, so that those no longer in its limits are removed
+var resources = struct {
+	sync.Mutex
+	byProject map[string][]corev1.ResourceName
+	byBudget  map[string][]corev1.ResourceName
+}{byProject: map[string][]corev1.ResourceName{}, byBudget: map[string][]corev1.ResourceName{}}
+
+func init() {
+	metrics.Registry.MustRegister(ProjectLimit, ProjectAllocated, ProjectUsed, ClusterBudgetCapacity, ClusterBudgetCommitted, AdmissionDecisions)
+}
+
+// SetProjectBudget publishes the limits of the project with what is allocated
+// and used of them, as reported in its status.
+func SetProjectBudget(project *projectv1.Project) {
+	resources.Lock()
+	defer resources.Unlock()
+
+	for _, name := range resources.byProject[project.Name] {
+		if _, ok := project.Spec.ProjectLimits[name]; !ok {
+			deleteProjectResource(project.Name, name)
+		}
+	}
+
+	names := make([]corev1.ResourceName, 0, len(project.Spec.ProjectLimits))
+	for name, limit := range project.Spec.ProjectLimits {
+		names = append(names, name)
+		ProjectLimit.WithLabelValues(project.Name, string(name)).Set(value(limit))
+		ProjectAllocated.WithLabelValues(project.Name, string(name)).Set(value(project.Status.Allocated[name]))
+		ProjectUsed.WithLabelValues(project.Name, string(name)).Set(value(project.Status.Used[name]))
+	}
+	resources.byProject[project.Name] = names
+}
+
+// DeleteProject removes the budget of a deleted project.
+func DeleteProject(projectName string) {
+	resources.Lock()
+	defer resources.Unlock()
+
+	for _, name := range resources.byProject[projectName] {
+		deleteProjectResource(projectName, name)
+	}
+	delete(resources.byProject, projectName)
+}
+
+func deleteProjectResource(projectName string, name corev1.ResourceName) {
+	ProjectLimit.DeleteLabelValues(projectName, string(name))
+	ProjectAllocated.DeleteLabelValues(projectName, string(name))
+	ProjectUsed.DeleteLabelValues(projectName, string(name))
+}
+
+// SetClusterBudget publishes the capacity of the cluster budget with what the
+// projects commit of it, as reported in its status.
+func SetClusterBudget(clusterBudget *projectv1.ClusterBudget) {
+	resources.Lock()
+	defer resources.Unlock()
+
+	for _, name := range resources.byBudget[clusterBudget.Name] {
+		if _, ok := clusterBudget.Status.Capacity[name]; !ok {
+			deleteClusterBudgetResource(clusterBudget.Name, name)
+		}
+	}
+
+	names := make([]corev1.ResourceName, 0, len(clusterBudget.Status.Capacity))
+	for name, capacity := range clusterBudget.Status.Capacity {
+		names = append(names, name)
+		ClusterBudgetCapacity.WithLabelValues(clusterBudget.Name, string(name)).Set(value(capacity))
+		ClusterBudgetCommitted.WithLabelValues(clusterBudget.Name, string(name)).Set(value(clusterBudget.Status.Committed[name]))
+	}
+	resources.byBudget[clusterBudget.Name] = names
+}
+
+// DeleteClusterBudget removes the capacity of a deleted cluster budget.
+func DeleteClusterBudget(budgetName string) {
+	resources.Lock()
+	defer resources.Unlock()
+
+	for _, name := range resources.byBudget[budgetName] {
+		deleteClusterBudgetResource(budgetName, name)
+	}
+	delete(resources.byBudget, budgetName)
+}
+
+func deleteClusterBudgetResource(budgetName string, name corev1.ResourceName) {
+	ClusterBudgetCapacity.DeleteLabelValues(budgetName, string(name))
+	ClusterBudgetCommitted.DeleteLabelValues(budgetName, string(name))
+}
+
+// value is the quantity as a float, cores for cpu and bytes for memory
+func value(quantity resource.Quantity) float64 {
+	return float64(quantity.MilliValue()) / 1000
+}
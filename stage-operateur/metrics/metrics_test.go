@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	projectv1 "project/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("SetProjectBudget", func() {
+	It("should publish the limits, allocation and usage of the project", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: v1.ObjectMeta{Name: "project-budget"},
+			Spec: projectv1.ProjectSpec{ProjectLimits: corev1.ResourceList{
+				corev1.ResourceLimitsCPU:    resource.MustParse("4"),
+				corev1.ResourceLimitsMemory: resource.MustParse("1Gi"),
+			}},
+			Status: projectv1.ProjectStatus{
+				Allocated: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1500m")},
+				Used:      corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("500m")},
+			},
+		}
+
+		// When
+		SetProjectBudget(&project)
+
+		// Then
+		Expect(testutil.ToFloat64(ProjectLimit.WithLabelValues("project-budget", "limits.cpu"))).To(Equal(4.0))
+		Expect(testutil.ToFloat64(ProjectLimit.WithLabelValues("project-budget", "limits.memory"))).To(Equal(1073741824.0))
+		Expect(testutil.ToFloat64(ProjectAllocated.WithLabelValues("project-budget", "limits.cpu"))).To(Equal(1.5))
+		Expect(testutil.ToFloat64(ProjectUsed.WithLabelValues("project-budget", "limits.cpu"))).To(Equal(0.5))
+	})
+
+	It("should remove the resources no longer in the limits", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: v1.ObjectMeta{Name: "project-shrunk"},
+			Spec: projectv1.ProjectSpec{ProjectLimits: corev1.ResourceList{
+				corev1.ResourceLimitsCPU:    resource.MustParse("4"),
+				corev1.ResourceLimitsMemory: resource.MustParse("1Gi"),
+			}},
+		}
+		SetProjectBudget(&project)
+		series := seriesCount(ProjectLimit)
+
+		// When
+		delete(project.Spec.ProjectLimits, corev1.ResourceLimitsMemory)
+		SetProjectBudget(&project)
+
+		// Then
+		Expect(seriesCount(ProjectLimit)).To(Equal(series - 1))
+	})
+})
+
+var _ = Describe("DeleteProject", func() {
+	It("should remove the budget of the project", func() {
+		// Given
+		project := projectv1.Project{
+			ObjectMeta: v1.ObjectMeta{Name: "project-deleted"},
+			Spec:       projectv1.ProjectSpec{ProjectLimits: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("4")}},
+		}
+		SetProjectBudget(&project)
+		series := seriesCount(ProjectUsed)
+
+		// When
+		DeleteProject("project-deleted")
+
+		// Then
+		Expect(seriesCount(ProjectUsed)).To(Equal(series - 1))
+	})
+})
+
+var _ = Describe("SetClusterBudget", func() {
+	It("should publish the capacity of the cluster budget and what is committed", func() {
+		// Given
+		clusterBudget := projectv1.ClusterBudget{
+			ObjectMeta: v1.ObjectMeta{Name: "cluster"},
+			Status: projectv1.ClusterBudgetStatus{
+				Capacity:  corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("64")},
+				Committed: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("72")},
+			},
+		}
+
+		// When
+		SetClusterBudget(&clusterBudget)
+
+		// Then
+		Expect(testutil.ToFloat64(ClusterBudgetCapacity.WithLabelValues("cluster", "requests.cpu"))).To(Equal(64.0))
+		Expect(testutil.ToFloat64(ClusterBudgetCommitted.WithLabelValues("cluster", "requests.cpu"))).To(Equal(72.0))
+
+		// When
+		DeleteClusterBudget("cluster")
+
+		// Then
+		Expect(seriesCount(ClusterBudgetCapacity)).To(Equal(0))
+	})
+})
+
+func seriesCount(collector prometheus.Collector) int {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(ch)
+		close(ch)
+	}()
+	count := 0
+	for range ch {
+		count++
+	}
+	return count
+}
@@ -0,0 +1,103 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	projectv1 "project/api/v1"
+	"project/budget"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// allowOrDenyClusterBudgets checks the limits of a project without parent
+// against every ClusterBudget that denies over-commitment. Children are
+// carved from their parent and are not checked again.
+func allowOrDenyClusterBudgets(ctx context.Context, c client.Client, oldProject *projectv1.Project, project projectv1.Project) admission.Response {
+	if project.Spec.Parent != "" {
+		return admission.Allowed("project limits are carved from its parent")
+	}
+
+	clusterBudgets := projectv1.ClusterBudgetList{}
+	if err := c.List(ctx, &clusterBudgets); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	var enforced []projectv1.ClusterBudget
+	nodeAllocatable := false
+	for _, clusterBudget := range clusterBudgets.Items {
+		if clusterBudget.EnforcementOrDefault() == projectv1.ClusterBudgetDeny {
+			enforced = append(enforced, clusterBudget)
+			nodeAllocatable = nodeAllocatable || clusterBudget.Spec.NodeAllocatable
+		}
+	}
+	if len(enforced) == 0 {
+		return admission.Allowed("no ClusterBudget to check the project limits against")
+	}
+
+	var allocatable corev1.ResourceList
+	if nodeAllocatable {
+		nodes := corev1.NodeList{}
+		if err := c.List(ctx, &nodes); err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		allocatable = budget.NodeAllocatable(nodes.Items)
+	}
+
+	projects := projectv1.ProjectList{}
+	if err := c.List(ctx, &projects); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	roots := projectv1.RootProjects(projects.Items)
+
+	for _, clusterBudget := range enforced {
+		var nodeCapacity corev1.ResourceList
+		if clusterBudget.Spec.NodeAllocatable {
+			nodeCapacity = allocatable
+		}
+		capacity := budget.ClusterCapacity(clusterBudget.Spec.Capacity, nodeCapacity)
+		if response := allowOrDenyClusterCapacity(oldProject, project, clusterBudget.Name, capacity, roots); !response.Allowed {
+			return response
+		}
+	}
+	return admission.Allowed("project limits fit in the capacity of the cluster")
+}
+
+// allowOrDenyClusterCapacity denies a project without parent whose limits do
+// not fit in what the capacity has left once the other projects without
+// parent are accounted for. Limits that were already over and did not grow
+// do not block the update.
+func allowOrDenyClusterCapacity(oldProject *projectv1.Project, project projectv1.Project, budgetName string, capacity corev1.ResourceList, roots []projectv1.Project) admission.Response {
+	quotas := make([]corev1.ResourceQuota, 0, len(roots)+1)
+	for _, root := range roots {
+		if root.Name != project.Name {
+			quotas = append(quotas, corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{Hard: root.Spec.ProjectLimits}})
+		}
+	}
+	quotas = append(quotas, corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{Hard: project.Spec.ProjectLimits}})
+
+	var oldLimits corev1.ResourceList
+	if oldProject != nil && oldProject.Spec.Parent == "" {
+		oldLimits = oldProject.Spec.ProjectLimits
+	}
+	exceeded := budget.Overrun(capacity, quotas, oldLimits, project.Spec.ProjectLimits)
+	if len(exceeded) == 0 {
+		return admission.Allowed("project limits fit in the capacity of ClusterBudget " + budgetName)
+	}
+	return denied(ReasonClusterBudgetExceeded, "project limits do not fit in the capacity of ClusterBudget "+budgetName+": "+budget.Join(exceeded))
+}
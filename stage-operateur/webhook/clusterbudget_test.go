@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	projectv1 "project/api/v1"
+)
+
+var _ = Describe("Testing allowOrDenyClusterCapacity function", func() {
+
+	capacity := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("100")}
+	other := setProject(60, 0)
+	other.Name = "other"
+
+	It("Should allow a project whose limits fit in the remaining capacity", func() {
+		//Given
+		project := setProject(40, 0)
+
+		reason := metav1.StatusReason("project limits fit in the capacity of ClusterBudget cluster")
+
+		//When
+		result := allowOrDenyClusterCapacity(nil, project, "cluster", capacity, []projectv1.Project{other, project})
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.Result.Reason).To(Equal(reason))
+	})
+
+	It("Should deny a project whose limits exceed the remaining capacity", func() {
+		//Given
+		oldProject := setProject(40, 0)
+		project := setProject(41, 0)
+
+		message := "project limits do not fit in the capacity of ClusterBudget cluster: limits.cpu"
+
+		//When
+		result := allowOrDenyClusterCapacity(&oldProject, project, "cluster", capacity, []projectv1.Project{other, oldProject})
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonClusterBudgetExceeded))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should count a child becoming a project without parent as new", func() {
+		//Given
+		oldProject := setProject(41, 0)
+		oldProject.Spec.Parent = "other"
+		project := setProject(41, 0)
+
+		//When
+		result := allowOrDenyClusterCapacity(&oldProject, project, "cluster", capacity, []projectv1.Project{other})
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+	})
+
+	It("Should not block an update leaving limits over the capacity untouched", func() {
+		//Given
+		project := setProject(50, 0)
+
+		//When
+		result := allowOrDenyClusterCapacity(&project, project, "cluster", capacity, []projectv1.Project{other, project})
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+})
@@ -0,0 +1,213 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"net/http"
+	projectv1 "project/api/v1"
+	"project/budget"
+	"project/index"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ancestorProjects returns the parent of the project, its parent, and so on,
+// up to a project without parent or whose parent does not exist.
+func ancestorProjects(ctx context.Context, c client.Client, project projectv1.Project) ([]projectv1.Project, error) {
+	var ancestors []projectv1.Project
+	visited := map[string]bool{project.Name: true}
+	for parentName := project.Spec.Parent; parentName != "" && !visited[parentName]; {
+		parent := projectv1.Project{}
+		err := c.Get(ctx, client.ObjectKey{Name: parentName}, &parent)
+		if errors.IsNotFound(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		visited[parentName] = true
+		ancestors = append(ancestors, parent)
+		parentName = parent.Spec.Parent
+	}
+	return ancestors, nil
+}
+
+// childProjects returns the children of the named project, found through the
+// field index index.ProjectParent.
+func childProjects(ctx context.Context, c client.Client, name string) ([]projectv1.Project, error) {
+	projectList := projectv1.ProjectList{}
+	if err := c.List(ctx, &projectList, client.MatchingFields{index.ProjectParent: name}); err != nil {
+		return nil, err
+	}
+	return projectv1.ChildProjects(projectList.Items, name), nil
+}
+
+// subtreeResourceQuotas returns the counted ResourceQuotas of the namespaces
+// of the project and of all its descendants.
+func subtreeResourceQuotas(ctx context.Context, c client.Client, project projectv1.Project, mode budget.AccountingMode, names projectv1.Names) (corev1.ResourceQuotaList, error) {
+	resourceQuotaList := corev1.ResourceQuotaList{}
+	visited := map[string]bool{project.Name: true}
+	for queue := []projectv1.Project{project}; len(queue) > 0; queue = queue[1:] {
+		quotas, err := allResourceQuotasInProject(ctx, c, queue[0], mode, names)
+		if err != nil {
+			return resourceQuotaList, err
+		}
+		resourceQuotaList.Items = append(resourceQuotaList.Items, quotas.Items...)
+
+		children, err := childProjects(ctx, c, queue[0].Name)
+		if err != nil {
+			return resourceQuotaList, err
+		}
+		for _, child := range children {
+			if !visited[child.Name] {
+				visited[child.Name] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	return resourceQuotaList, nil
+}
+
+// childrenResourceQuotas returns the limits of the children of the project as
+// ResourceQuotas, so that what they carve counts against the project limits.
+func childrenResourceQuotas(ctx context.Context, c client.Client, project projectv1.Project) ([]corev1.ResourceQuota, error) {
+	children, err := childProjects(ctx, c, project.Name)
+	if err != nil {
+		return nil, err
+	}
+	var quotas []corev1.ResourceQuota
+	for _, child := range children {
+		quota := corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{Hard: child.Spec.ProjectLimits}}
+		quota.Name = child.Name
+		quotas = append(quotas, quota)
+	}
+	return quotas, nil
+}
+
+// allowOrDenyInAncestors checks the resourceQuota against the limits of every
+// ancestor of the project, summing the quotas of the ancestor's whole
+// subtree. The project's own response is returned when they all allow it.
+func allowOrDenyInAncestors(ctx context.Context, c client.Client, project projectv1.Project, quota corev1.ResourceQuota, oldQuota *corev1.ResourceQuota, mode budget.AccountingMode, names projectv1.Names, response admission.Response) admission.Response {
+	ancestors, err := ancestorProjects(ctx, c, project)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	for _, ancestor := range ancestors {
+		resourceQuotaList, err := subtreeResourceQuotas(ctx, c, ancestor, mode, names)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		ancestorResponse := allowOrDenyUpdateOrCreate(ancestor, quota, oldQuota, perNamespace(withResourceQuota(resourceQuotaList, quota)))
+		if !ancestorResponse.Allowed {
+			return denied(ReasonAncestorLimits, "ancestor project "+ancestor.Name+": "+ancestorResponse.Result.Message)
+		}
+	}
+	return response
+}
+
+// allowOrDenyNamespaceInAncestors checks a namespace joining the project
+// against the limits of every ancestor of the project, summing the quotas of
+// the ancestor's whole subtree without the ones the namespace already counts
+// there. The project's own response is returned when they all allow it.
+func allowOrDenyNamespaceInAncestors(ctx context.Context, c client.Client, project projectv1.Project, namespaceQuota corev1.ResourceQuota, mode budget.AccountingMode, names projectv1.Names, response admission.Response) admission.Response {
+	ancestors, err := ancestorProjects(ctx, c, project)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	for _, ancestor := range ancestors {
+		resourceQuotaList, err := subtreeResourceQuotas(ctx, c, ancestor, mode, names)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		others := corev1.ResourceQuotaList{}
+		for _, quota := range resourceQuotaList.Items {
+			if quota.Namespace != namespaceQuota.Namespace {
+				others.Items = append(others.Items, quota)
+			}
+		}
+		ancestorResponse := allowOrDenyNamespaceMove(ancestor, namespaceQuota, perNamespace(others))
+		if !ancestorResponse.Allowed {
+			return denied(ReasonAncestorLimits, "ancestor project "+ancestor.Name+": "+ancestorResponse.Result.Message)
+		}
+	}
+	return response
+}
+
+// allowOrDenyCarving denies a child project whose limits do not fit in what
+// its parent has left once its own quotas and the other children are
+// accounted for. Limits that were already over and did not grow do not block
+// the update.
+func allowOrDenyCarving(oldProject *projectv1.Project, project projectv1.Project, parent projectv1.Project, parentResourceQuotas corev1.ResourceQuotaList) admission.Response {
+	quotas := make([]corev1.ResourceQuota, 0, len(parentResourceQuotas.Items)+1)
+	for _, quota := range parentResourceQuotas.Items {
+		if quota.Namespace == "" && quota.Name == project.Name {
+			continue
+		}
+		quotas = append(quotas, quota)
+	}
+	quotas = append(quotas, corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{Hard: project.Spec.ProjectLimits}})
+
+	var oldLimits corev1.ResourceList
+	if oldProject != nil && oldProject.Spec.Parent == parent.Name {
+		oldLimits = oldProject.Spec.ProjectLimits
+	}
+	exceeded := budget.Overrun(parent.Spec.ProjectLimits, quotas, oldLimits, project.Spec.ProjectLimits)
+	if len(exceeded) == 0 {
+		return admission.Allowed("project limits fit in the remaining budget of parent project " + parent.Name)
+	}
+	return denied(ReasonParentLimits, "project limits do not fit in the remaining budget of parent project "+parent.Name+": "+budget.Join(exceeded))
+}
+
+// allowOrDenyParent denies a project whose parent does not exist, would make
+// a cycle, or has not enough budget left for the project limits.
+func allowOrDenyParent(ctx context.Context, c client.Client, oldProject *projectv1.Project, project projectv1.Project, mode budget.AccountingMode, names projectv1.Names) admission.Response {
+	if project.Spec.Parent == project.Name {
+		return denied(ReasonInvalidParent, "project cannot be its own parent")
+	}
+
+	parent := projectv1.Project{}
+	err := c.Get(ctx, client.ObjectKey{Name: project.Spec.Parent}, &parent)
+	if errors.IsNotFound(err) {
+		return denied(ReasonProjectNotFound, "parent project "+project.Spec.Parent+" does not exist")
+	}
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	ancestors, err := ancestorProjects(ctx, c, parent)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.Name == project.Name {
+			return denied(ReasonInvalidParent, "parent project "+parent.Name+" is a descendant of the project")
+		}
+	}
+
+	resourceQuotaList, err := allResourceQuotasInProject(ctx, c, parent, mode, names)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	children, err := childrenResourceQuotas(ctx, c, parent)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	parentResourceQuotas := perNamespace(resourceQuotaList)
+	parentResourceQuotas.Items = append(parentResourceQuotas.Items, children...)
+
+	return allowOrDenyCarving(oldProject, project, parent, parentResourceQuotas)
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Testing allowOrDenyCarving function", func() {
+
+	parent := setProject(100, 10000)
+	parent.Name = "department"
+
+	ownQuota := setResourceQuota(40, 4000)
+	ownQuota.Namespace = "department-tools"
+	siblingQuota := corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{Hard: setProject(30, 3000).Spec.ProjectLimits}}
+	siblingQuota.Name = "team-a"
+	parentResourceQuotas := fillResourcequotaList(ownQuota, siblingQuota)
+
+	It("Should allow a child whose limits fit in the parent's remaining budget", func() {
+		//Given
+		project := setProject(30, 3000)
+		project.Name = "team-b"
+		project.Spec.Parent = parent.Name
+
+		reason := metav1.StatusReason("project limits fit in the remaining budget of parent project department")
+
+		//When
+		result := allowOrDenyCarving(nil, project, parent, parentResourceQuotas)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.Result.Reason).To(Equal(reason))
+	})
+
+	It("Should deny a child whose limits exceed the parent's remaining budget", func() {
+		//Given
+		project := setProject(31, 3000)
+		project.Name = "team-b"
+		project.Spec.Parent = parent.Name
+
+		message := "project limits do not fit in the remaining budget of parent project department: limits.cpu"
+
+		//When
+		result := allowOrDenyCarving(nil, project, parent, parentResourceQuotas)
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonParentLimits))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should not count the child's own previous limits against it", func() {
+		//Given
+		oldProject := setProject(30, 3000)
+		oldProject.Name = "team-a"
+		oldProject.Spec.Parent = parent.Name
+		project := setProject(60, 3000)
+		project.Name = "team-a"
+		project.Spec.Parent = parent.Name
+
+		//When
+		result := allowOrDenyCarving(&oldProject, project, parent, parentResourceQuotas)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+})
@@ -0,0 +1,271 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"net/http"
+	projectv1 "project/api/v1"
+	"project/budget"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-v1-namespace,mutating=false,failurePolicy=fail,groups="",resources=namespaces,verbs=create;update,versions=v1,name=vnamespace.kb.io
+
+// NamespaceValidator validates the project label of Namespaces
+type NamespaceValidator struct {
+	Client client.Client
+	// AuthorizedUsers and AuthorizedGroups may set, change or remove the
+	// project label of a namespace. When both are empty anyone who can create
+	// or update the namespace may.
+	AuthorizedUsers  []string
+	AuthorizedGroups []string
+	// OperatorUsername is the user the operator runs as, which creates the
+	// namespaces of NamespaceRequests whatever the authorized users.
+	OperatorUsername string
+	// AccountingMode selects the ResourceQuotas counted against the project limits
+	AccountingMode budget.AccountingMode
+	// Names are the project label and the project-quota name
+	Names   projectv1.Names
+	decoder *admission.Decoder
+}
+
+// namespace validator
+func (v *NamespaceValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	switch req.Operation {
+	case v1beta1.Create:
+		return v.validateCreate(ctx, req)
+	case v1beta1.Update:
+		return v.validateUpdate(ctx, req)
+	default:
+		return admission.Allowed("No specific logic for" + string(req.Operation) + " operations")
+	}
+}
+
+func (v *NamespaceValidator) validateCreate(ctx context.Context, req admission.Request) admission.Response {
+	namespace := corev1.Namespace{}
+	err := v.decoder.Decode(req, &namespace)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	projectName, ok := namespace.Labels[v.Names.Label()]
+	if !ok {
+		return admission.Allowed("namespace not related to project")
+	}
+
+	if req.UserInfo.Username != v.OperatorUsername && !relabelAuthorized(req.UserInfo, v.AuthorizedUsers, v.AuthorizedGroups) {
+		return denied(ReasonUnauthorized, "user "+req.UserInfo.Username+" is not authorized to create a namespace with the label 'project'")
+	}
+
+	project := projectv1.Project{}
+	err = v.Client.Get(ctx, client.ObjectKey{Name: projectName}, &project)
+	if errors.IsNotFound(err) {
+		return denied(ReasonProjectNotFound, "label 'project' points at Project "+projectName+" which does not exist")
+	}
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	// The namespace is refused when the project-quota the operator creates in
+	// it from the project's defaultNamespaceQuota, or from the quota of the
+	// NamespaceRequest it is created for, pushes the project over its limits.
+	namespaceQuota := corev1.ResourceQuota{Spec: project.NamespaceQuotaSpec()}
+	if req.UserInfo.Username == v.OperatorUsername {
+		request, err := v.getNamespaceRequest(ctx, &namespace)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if request != nil {
+			namespaceQuota.Spec = request.QuotaSpec(&project)
+		}
+	}
+	namespaceQuota.Name = v.Names.QuotaName()
+	namespaceQuota.Namespace = namespace.Name
+
+	resourceQuotaList, err := allResourceQuotasInProject(ctx, v.Client, project, v.AccountingMode, v.Names)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	response := allowOrDenyNamespaceMove(project, namespaceQuota, perNamespace(resourceQuotaList))
+	if response.Allowed {
+		response = allowOrDenyNamespaceInAncestors(ctx, v.Client, project, namespaceQuota, v.AccountingMode, v.Names, response)
+	}
+	return response
+}
+
+func (v *NamespaceValidator) validateUpdate(ctx context.Context, req admission.Request) admission.Response {
+	namespace := corev1.Namespace{}
+	oldNamespace := corev1.Namespace{}
+	err := v.decoder.Decode(req, &namespace)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	err = v.decoder.DecodeRaw(req.OldObject, &oldNamespace)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	projectName := namespace.Labels[v.Names.Label()]
+	if projectName == oldNamespace.Labels[v.Names.Label()] {
+		return admission.Allowed("label 'project' unchanged")
+	}
+
+	// The operator removes the label from the namespaces of a deleted project
+	if projectName == "" {
+		oldProject, err := v.getProject(ctx, oldNamespace.Labels[v.Names.Label()])
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if oldProject != nil && oldProject.DeletionTimestamp != nil {
+			return admission.Allowed("namespace leaves its project, which is being deleted")
+		}
+	}
+
+	if !relabelAuthorized(req.UserInfo, v.AuthorizedUsers, v.AuthorizedGroups) {
+		return denied(ReasonUnauthorized, "user "+req.UserInfo.Username+" is not authorized to change the label 'project' of a namespace")
+	}
+
+	if projectName == "" {
+		return admission.Allowed("namespace leaves its project")
+	}
+
+	project := projectv1.Project{}
+	err = v.Client.Get(ctx, client.ObjectKey{Name: projectName}, &project)
+	if errors.IsNotFound(err) {
+		return denied(ReasonProjectNotFound, "label 'project' points at Project "+projectName+" which does not exist")
+	}
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	namespaceQuotas, err := namespaceResourceQuotas(ctx, v.Client, namespace.Name, budget.AllQuotas, v.Names)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	var counted []corev1.ResourceQuota
+	for _, quota := range namespaceQuotas {
+		if v.AccountingMode.Counts(v.Names, quota.Name) {
+			counted = append(counted, quota)
+		} else if v.AccountingMode == budget.Exclusive {
+			return denied(ReasonForeignQuota, "namespace has resourceQuota "+quota.Name+", only the project-quota resourceQuota is allowed in the namespaces of a project")
+		}
+	}
+	if len(counted) == 0 {
+		return admission.Allowed("namespace has no project-quota to move")
+	}
+
+	resourceQuotaList, err := allResourceQuotasInProject(ctx, v.Client, project, v.AccountingMode, v.Names)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	namespaceQuota := budget.Effective(counted)
+	response := allowOrDenyNamespaceMove(project, namespaceQuota, perNamespace(resourceQuotaList))
+	if response.Allowed {
+		response = allowOrDenyNamespaceInAncestors(ctx, v.Client, project, namespaceQuota, v.AccountingMode, v.Names, response)
+	}
+	return response
+}
+
+// getProject returns the named project, or nil when it does not exist.
+func (v *NamespaceValidator) getProject(ctx context.Context, name string) (*projectv1.Project, error) {
+	if name == "" {
+		return nil, nil
+	}
+	project := projectv1.Project{}
+	err := v.Client.Get(ctx, client.ObjectKey{Name: name}, &project)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// getNamespaceRequest returns the NamespaceRequest the namespace is created
+// for, or nil when it has none.
+func (v *NamespaceValidator) getNamespaceRequest(ctx context.Context, namespace *corev1.Namespace) (*projectv1.NamespaceRequest, error) {
+	key, ok := projectv1.NamespaceRequestOf(namespace)
+	if !ok {
+		return nil, nil
+	}
+	request := projectv1.NamespaceRequest{}
+	err := v.Client.Get(ctx, key, &request)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// relabelAuthorized reports whether the user may change the project label of
+// a namespace.
+func relabelAuthorized(userInfo authenticationv1.UserInfo, users []string, groups []string) bool {
+	if len(users) == 0 && len(groups) == 0 {
+		return true
+	}
+	for _, user := range users {
+		if user == userInfo.Username {
+			return true
+		}
+	}
+	for _, group := range groups {
+		for _, userGroup := range userInfo.Groups {
+			if group == userGroup {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowOrDenyNamespaceMove denies moving a namespace into a project when its
+// project-quota would push the project over one of its limits.
+func allowOrDenyNamespaceMove(project projectv1.Project, namespaceQuota corev1.ResourceQuota, allResourceQuotas corev1.ResourceQuotaList) admission.Response {
+	quotas := append(append([]corev1.ResourceQuota{}, allResourceQuotas.Items...), namespaceQuota)
+
+	var exceeded []corev1.ResourceName
+	for _, name := range budget.Exceeded(budget.Allocatable(project.Spec.ProjectLimits, project.Spec.Overcommit), budget.Overcommitted(quotas, project.Spec.Overcommit)) {
+		amount := budget.Amount(namespaceQuota.Spec.Hard, name)
+		if amount.Sign() > 0 {
+			exceeded = append(exceeded, name)
+		}
+	}
+
+	if len(exceeded) == 0 {
+		return admission.Allowed("namespace project-quota fits in the project's limits")
+	}
+	return denied(ReasonLimitsExceeded, "adding the namespace to project "+project.Name+" exceeds its limits: "+budget.Join(exceeded))
+}
+
+// NamespaceValidator implements admission.DecoderInjector.
+// A decoder will be automatically injected.
+
+// InjectDecoder injects the decoder.
+func (v *NamespaceValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
@@ -0,0 +1,312 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	projectv1 "project/api/v1"
+	"project/index"
+)
+
+// indexedClient lists the namespaces of a project by their label, as the
+// field index index.NamespaceProject does in the manager's cache.
+type indexedClient struct {
+	client.Client
+}
+
+func (c indexedClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.FieldSelector != nil {
+		if projectName, ok := listOpts.FieldSelector.RequiresExactMatch(index.NamespaceProject); ok {
+			return c.Client.List(ctx, list, client.MatchingLabels{projectv1.ProjectLabel: projectName})
+		}
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+// namespaceCreateRequest is the admission request of a user creating the
+// namespace.
+func namespaceCreateRequest(namespace corev1.Namespace, username string) admission.Request {
+	raw, err := json.Marshal(namespace)
+	Expect(err).NotTo(HaveOccurred())
+	return admission.Request{AdmissionRequest: v1beta1.AdmissionRequest{
+		Operation: v1beta1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+		UserInfo:  authenticationv1.UserInfo{Username: username},
+	}}
+}
+
+// namespaceUpdateRequest is the admission request of a user updating the
+// namespace.
+func namespaceUpdateRequest(oldNamespace corev1.Namespace, namespace corev1.Namespace, username string) admission.Request {
+	request := namespaceCreateRequest(namespace, username)
+	raw, err := json.Marshal(oldNamespace)
+	Expect(err).NotTo(HaveOccurred())
+	request.Operation = v1beta1.Update
+	request.OldObject = runtime.RawExtension{Raw: raw}
+	return request
+}
+
+var _ = Describe("Testing relabelAuthorized function", func() {
+
+	user := authenticationv1.UserInfo{Username: "alice", Groups: []string{"system:authenticated", "platform"}}
+
+	It("Should authorize everyone when no user or group is configured", func() {
+		Expect(relabelAuthorized(user, nil, nil)).To(BeTrue())
+	})
+
+	It("Should authorize a configured user", func() {
+		Expect(relabelAuthorized(user, []string{"bob", "alice"}, nil)).To(BeTrue())
+	})
+
+	It("Should authorize a member of a configured group", func() {
+		Expect(relabelAuthorized(user, []string{"bob"}, []string{"platform"})).To(BeTrue())
+	})
+
+	It("Should not authorize other users", func() {
+		Expect(relabelAuthorized(user, []string{"bob"}, []string{"admins"})).To(BeFalse())
+	})
+})
+
+var _ = Describe("Testing NamespaceValidator", func() {
+
+	labelled := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test1", Labels: map[string]string{"project": "project-1"}}}
+
+	newValidator := func(objects ...runtime.Object) *NamespaceValidator {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(projectv1.AddToScheme(scheme)).To(Succeed())
+		decoder, err := admission.NewDecoder(scheme)
+		Expect(err).NotTo(HaveOccurred())
+		validator := &NamespaceValidator{
+			Client:           indexedClient{fake.NewFakeClientWithScheme(scheme, objects...)},
+			AuthorizedUsers:  []string{"bob"},
+			OperatorUsername: "system:serviceaccount:system:default",
+		}
+		Expect(validator.InjectDecoder(decoder)).To(Succeed())
+		return validator
+	}
+
+	It("Should deny a user who may not set the project label to create a namespace with it", func() {
+		//Given
+		validator := newValidator()
+		message := "user alice is not authorized to create a namespace with the label 'project'"
+
+		//When
+		result := validator.Handle(context.Background(), namespaceCreateRequest(labelled, "alice"))
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonUnauthorized))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should deny to create a namespace whose defaultNamespaceQuota exceeds the project's limits", func() {
+		//Given
+		project := setProject(100, 10000)
+		defaultQuota := setResourceQuota(20, 0)
+		project.Spec.DefaultNamespaceQuota = &defaultQuota.Spec
+		existing := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test0", Labels: map[string]string{"project": "project-1"}}}
+		existingQuota := setResourceQuota(90, 0)
+		existingQuota.Namespace = "test0"
+		validator := newValidator(&project, &existing, &existingQuota)
+
+		message := "adding the namespace to project project-1 exceeds its limits: limits.cpu"
+
+		//When
+		result := validator.Handle(context.Background(), namespaceCreateRequest(labelled, "system:serviceaccount:system:default"))
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should allow to create a namespace whose defaultNamespaceQuota fits in the project's limits", func() {
+		//Given
+		project := setProject(100, 10000)
+		defaultQuota := setResourceQuota(10, 0)
+		project.Spec.DefaultNamespaceQuota = &defaultQuota.Spec
+		existing := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test0", Labels: map[string]string{"project": "project-1"}}}
+		existingQuota := setResourceQuota(90, 0)
+		existingQuota.Namespace = "test0"
+		validator := newValidator(&project, &existing, &existingQuota)
+
+		//When
+		result := validator.Handle(context.Background(), namespaceCreateRequest(labelled, "bob"))
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+
+	It("Should deny to create a namespace whose defaultNamespaceQuota exceeds the limits of an ancestor project", func() {
+		//Given
+		parent := setProject(100, 10000)
+		parent.Name = "parent"
+		project := setProject(50, 10000)
+		project.Spec.Parent = "parent"
+		defaultQuota := setResourceQuota(20, 0)
+		project.Spec.DefaultNamespaceQuota = &defaultQuota.Spec
+		parentNamespace := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test0", Labels: map[string]string{"project": "parent"}}}
+		parentQuota := setResourceQuota(90, 0)
+		parentQuota.Namespace = "test0"
+		validator := newValidator(&parent, &project, &parentNamespace, &parentQuota)
+
+		message := "ancestor project parent: adding the namespace to project parent exceeds its limits: limits.cpu"
+
+		//When
+		result := validator.Handle(context.Background(), namespaceCreateRequest(labelled, "bob"))
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonAncestorLimits))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should deny to move a namespace whose project-quota exceeds the limits of an ancestor project", func() {
+		//Given
+		parent := setProject(100, 10000)
+		parent.Name = "parent"
+		project := setProject(50, 10000)
+		project.Spec.Parent = "parent"
+		parentNamespace := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test0", Labels: map[string]string{"project": "parent"}}}
+		parentQuota := setResourceQuota(90, 0)
+		parentQuota.Namespace = "test0"
+		unlabelled := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test1"}}
+		movedQuota := setResourceQuota(20, 0)
+		movedQuota.Namespace = "test1"
+		validator := newValidator(&parent, &project, &parentNamespace, &parentQuota, &unlabelled, &movedQuota)
+
+		message := "ancestor project parent: adding the namespace to project parent exceeds its limits: limits.cpu"
+
+		//When
+		result := validator.Handle(context.Background(), namespaceUpdateRequest(unlabelled, labelled, "bob"))
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonAncestorLimits))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should check the quota of the NamespaceRequest a namespace is created for instead of the defaultNamespaceQuota", func() {
+		//Given
+		project := setProject(100, 10000)
+		defaultQuota := setResourceQuota(20, 0)
+		project.Spec.DefaultNamespaceQuota = &defaultQuota.Spec
+		existing := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test0", Labels: map[string]string{"project": "project-1"}}}
+		existingQuota := setResourceQuota(90, 0)
+		existingQuota.Namespace = "test0"
+		requestedQuota := setResourceQuota(10, 0)
+		request := projectv1.NamespaceRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "request", Namespace: "test0"},
+			Spec:       projectv1.NamespaceRequestSpec{Namespace: "test1", Quota: requestedQuota.Spec.Hard},
+		}
+		validator := newValidator(&project, &existing, &existingQuota, &request)
+		requested := *labelled.DeepCopy()
+		requested.Annotations = map[string]string{projectv1.NamespaceRequestAnnotation: "test0/request"}
+
+		//When
+		result := validator.Handle(context.Background(), namespaceCreateRequest(requested, "system:serviceaccount:system:default"))
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+
+	It("Should deny to create a namespace whose NamespaceRequest quota exceeds the project's limits", func() {
+		//Given
+		project := setProject(100, 10000)
+		existing := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test0", Labels: map[string]string{"project": "project-1"}}}
+		existingQuota := setResourceQuota(90, 0)
+		existingQuota.Namespace = "test0"
+		requestedQuota := setResourceQuota(20, 0)
+		request := projectv1.NamespaceRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "request", Namespace: "test0"},
+			Spec:       projectv1.NamespaceRequestSpec{Namespace: "test1", Quota: requestedQuota.Spec.Hard},
+		}
+		validator := newValidator(&project, &existing, &existingQuota, &request)
+		requested := *labelled.DeepCopy()
+		requested.Annotations = map[string]string{projectv1.NamespaceRequestAnnotation: "test0/request"}
+
+		message := "adding the namespace to project project-1 exceeds its limits: limits.cpu"
+
+		//When
+		result := validator.Handle(context.Background(), namespaceCreateRequest(requested, "system:serviceaccount:system:default"))
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should allow anyone to create a namespace without the project label", func() {
+		//Given
+		validator := newValidator()
+		unlabelled := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test1"}}
+
+		//When
+		result := validator.Handle(context.Background(), namespaceCreateRequest(unlabelled, "alice"))
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+})
+
+var _ = Describe("Testing allowOrDenyNamespaceMove function", func() {
+
+	project := setProject(100, 10000)
+	allResourceQuotas := fillResourcequotaList(setResourceQuota(10, 1000), setResourceQuota(80, 8000))
+
+	It("Should allow to move a namespace whose project-quota fits in the project's limits", func() {
+		//Given
+		namespaceQuota := setResourceQuota(10, 1000)
+
+		reason := metav1.StatusReason("namespace project-quota fits in the project's limits")
+
+		//When
+		result := allowOrDenyNamespaceMove(project, namespaceQuota, allResourceQuotas)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.Result.Reason).To(Equal(reason))
+	})
+
+	It("Should deny to move a namespace whose project-quota exceeds the project's limits", func() {
+		//Given
+		namespaceQuota := setResourceQuota(11, 1000)
+
+		message := "adding the namespace to project project-1 exceeds its limits: limits.cpu"
+
+		//When
+		result := allowOrDenyNamespaceMove(project, namespaceQuota, allResourceQuotas)
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should allow to move an empty namespace into a project that is already over its limits", func() {
+		//Given
+		overCommitted := fillResourcequotaList(setResourceQuota(101, 0))
+		namespaceQuota := setResourceQuota(0, 0)
+
+		//When
+		result := allowOrDenyNamespaceMove(project, namespaceQuota, overCommitted)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+})
@@ -0,0 +1,381 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"net/http"
+	projectv1 "project/api/v1"
+	"project/budget"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sort"
+	"strings"
+)
+
+// +kubebuilder:webhook:path=/validate-v1-project,mutating=false,failurePolicy=fail,groups=project.my.domain,resources=projects,verbs=create;update;delete,versions=v1,name=vproject.kb.io
+
+// ProjectValidator validates Projects
+type ProjectValidator struct {
+	Client client.Client
+	// AllowShrinkBelowAllocation lets projectLimits be lowered below what the
+	// namespaces' project-quotas already allocate. The project is then
+	// reported OverCommitted instead of the update being denied.
+	AllowShrinkBelowAllocation bool
+	// AccountingMode selects the ResourceQuotas counted against the project limits
+	AccountingMode budget.AccountingMode
+	// Names are the project label and the project-quota name
+	Names   projectv1.Names
+	decoder *admission.Decoder
+}
+
+// project validator
+func (v *ProjectValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	switch req.Operation {
+	case v1beta1.Create:
+		return v.validateCreate(ctx, req)
+	case v1beta1.Update:
+		return v.validateUpdate(ctx, req)
+	case v1beta1.Delete:
+		return v.validateDelete(ctx, req)
+	default:
+		return admission.Allowed("No specific logic for" + string(req.Operation) + " operations")
+	}
+}
+
+func (v *ProjectValidator) validateCreate(ctx context.Context, req admission.Request) admission.Response {
+	project := projectv1.Project{}
+	err := v.decoder.Decode(req, &project)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if problems := validateProjectLimits(project.Spec.ProjectLimits); len(problems) > 0 {
+		return denied(ReasonInvalidSpec, "invalid projectLimits: "+strings.Join(problems, "; "))
+	}
+	if problems := validateNamespaceTemplate(project.Spec.NamespaceTemplate); len(problems) > 0 {
+		return denied(ReasonInvalidSpec, "invalid namespaceTemplate: "+strings.Join(problems, "; "))
+	}
+	if problems := validateProjectMembers(project.Spec.Members); len(problems) > 0 {
+		return denied(ReasonInvalidSpec, "invalid members: "+strings.Join(problems, "; "))
+	}
+	if problems := validateDefaultNamespaceQuota(project.Spec.DefaultNamespaceQuota); len(problems) > 0 {
+		return denied(ReasonInvalidSpec, "invalid defaultNamespaceQuota: "+strings.Join(problems, "; "))
+	}
+	if problems := validateOvercommit(project.Spec.ProjectLimits, project.Spec.Overcommit); len(problems) > 0 {
+		return denied(ReasonInvalidSpec, "invalid overcommit: "+strings.Join(problems, "; "))
+	}
+	if response := allowOrDenyDefaultNamespaceQuota(nil, project, corev1.ResourceQuotaList{}); !response.Allowed {
+		return response
+	}
+	if project.Spec.Parent != "" {
+		if response := allowOrDenyParent(ctx, v.Client, nil, project, v.AccountingMode, v.Names); !response.Allowed {
+			return response
+		}
+	}
+	if response := allowOrDenyClusterBudgets(ctx, v.Client, nil, project); !response.Allowed {
+		return response
+	}
+	return admission.Allowed("project limits are valid")
+}
+
+func (v *ProjectValidator) validateUpdate(ctx context.Context, req admission.Request) admission.Response {
+	project := projectv1.Project{}
+	oldProject := projectv1.Project{}
+	err := v.decoder.Decode(req, &project)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	err = v.decoder.DecodeRaw(req.OldObject, &oldProject)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	// The finalizer of a deleted project is removed whatever its spec, and
+	// the fields an update leaves as they are are not checked again, so that
+	// a project whose parent is gone can still be updated and finalized.
+	if project.DeletionTimestamp != nil {
+		return admission.Allowed("project is being deleted")
+	}
+	limitsChanged := !equality.Semantic.DeepEqual(oldProject.Spec.ProjectLimits, project.Spec.ProjectLimits)
+	overcommitChanged := !equality.Semantic.DeepEqual(oldProject.Spec.Overcommit, project.Spec.Overcommit)
+	defaultQuotaChanged := !equality.Semantic.DeepEqual(oldProject.Spec.DefaultNamespaceQuota, project.Spec.DefaultNamespaceQuota)
+	parentChanged := oldProject.Spec.Parent != project.Spec.Parent
+
+	if problems := validateProjectLimits(project.Spec.ProjectLimits); limitsChanged && len(problems) > 0 {
+		return denied(ReasonInvalidSpec, "invalid projectLimits: "+strings.Join(problems, "; "))
+	}
+	if !equality.Semantic.DeepEqual(oldProject.Spec.NamespaceTemplate, project.Spec.NamespaceTemplate) {
+		if problems := validateNamespaceTemplate(project.Spec.NamespaceTemplate); len(problems) > 0 {
+			return denied(ReasonInvalidSpec, "invalid namespaceTemplate: "+strings.Join(problems, "; "))
+		}
+	}
+	if !equality.Semantic.DeepEqual(oldProject.Spec.Members, project.Spec.Members) {
+		if problems := validateProjectMembers(project.Spec.Members); len(problems) > 0 {
+			return denied(ReasonInvalidSpec, "invalid members: "+strings.Join(problems, "; "))
+		}
+	}
+	if problems := validateDefaultNamespaceQuota(project.Spec.DefaultNamespaceQuota); defaultQuotaChanged && len(problems) > 0 {
+		return denied(ReasonInvalidSpec, "invalid defaultNamespaceQuota: "+strings.Join(problems, "; "))
+	}
+	if problems := validateOvercommit(project.Spec.ProjectLimits, project.Spec.Overcommit); (limitsChanged || overcommitChanged) && len(problems) > 0 {
+		return denied(ReasonInvalidSpec, "invalid overcommit: "+strings.Join(problems, "; "))
+	}
+	if !limitsChanged && !overcommitChanged && !defaultQuotaChanged && !parentChanged {
+		return admission.Allowed("project budget unchanged")
+	}
+
+	resourceQuotaList, err := allResourceQuotasInProject(ctx, v.Client, project, v.AccountingMode, v.Names)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	children, err := childrenResourceQuotas(ctx, v.Client, project)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	allocated := perNamespace(resourceQuotaList)
+	allocated.Items = append(allocated.Items, children...)
+
+	if defaultQuotaChanged || limitsChanged || overcommitChanged {
+		if response := allowOrDenyDefaultNamespaceQuota(&oldProject, project, allocated); !response.Allowed {
+			return response
+		}
+	}
+
+	response := allowOrDenyLimitsUpdate(oldProject, project, allocated, v.AllowShrinkBelowAllocation)
+	if !response.Allowed {
+		return response
+	}
+	if !limitsChanged && !parentChanged {
+		return response
+	}
+	if clusterResponse := allowOrDenyClusterBudgets(ctx, v.Client, &oldProject, project); !clusterResponse.Allowed {
+		return clusterResponse
+	}
+	if project.Spec.Parent == "" {
+		return response
+	}
+	if parentResponse := allowOrDenyParent(ctx, v.Client, &oldProject, project, v.AccountingMode, v.Names); !parentResponse.Allowed {
+		return parentResponse
+	}
+	return response
+}
+
+// validateDelete denies deleting a project that still has children: they
+// would be left with a parent that does not exist.
+func (v *ProjectValidator) validateDelete(ctx context.Context, req admission.Request) admission.Response {
+	children, err := childProjects(ctx, v.Client, req.Name)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(children) == 0 {
+		return admission.Allowed("project has no children")
+	}
+	names := make([]string, 0, len(children))
+	for _, child := range children {
+		names = append(names, child.Name)
+	}
+	sort.Strings(names)
+	return denied(ReasonHasChildren, "project has children, detach or delete them first: "+strings.Join(names, ", "))
+}
+
+// validateProjectLimits returns a description of every project limit with an
+// unknown resource name or a negative quantity.
+func validateProjectLimits(limits corev1.ResourceList) []string {
+	var problems []string
+	for _, name := range sortedNames(limits) {
+		quantity := limits[name]
+		if !budget.Supported(name) {
+			problems = append(problems, "unknown resource name "+string(name))
+		}
+		if quantity.Sign() < 0 {
+			problems = append(problems, string(name)+" must not be negative")
+		}
+	}
+	return problems
+}
+
+// validateNamespaceTemplate returns a description of every template object
+// that cannot be stamped into the namespaces of the project.
+func validateNamespaceTemplate(template *projectv1.NamespaceTemplate) []string {
+	if template == nil {
+		return nil
+	}
+
+	var problems []string
+	for i, raw := range template.Objects {
+		object := unstructured.Unstructured{}
+		if err := object.UnmarshalJSON(raw.Raw); err != nil {
+			problems = append(problems, fmt.Sprintf("object %d: %v", i, err))
+			continue
+		}
+		if object.GetName() == "" {
+			problems = append(problems, fmt.Sprintf("object %d has no name", i))
+		}
+		if object.GetNamespace() != "" {
+			problems = append(problems, fmt.Sprintf("object %d must not set a namespace", i))
+		}
+		if !namespaceTemplateKind(object.GroupVersionKind()) {
+			problems = append(problems, fmt.Sprintf("object %d: kind %s of %s is not allowed in a namespace template", i, object.GetKind(), object.GetAPIVersion()))
+			continue
+		}
+		if object.GetKind() == "RoleBinding" && !projectRoleRef(object) {
+			problems = append(problems, fmt.Sprintf("object %d must bind the admin, edit or view ClusterRole", i))
+		}
+	}
+	return problems
+}
+
+func namespaceTemplateKind(gvk schema.GroupVersionKind) bool {
+	for _, kind := range projectv1.NamespaceTemplateKinds() {
+		if kind == gvk {
+			return true
+		}
+	}
+	return false
+}
+
+// projectRoleRef reports whether the RoleBinding binds the ClusterRole of a
+// project role, the only ones the operator may bind.
+func projectRoleRef(roleBinding unstructured.Unstructured) bool {
+	kind, _, _ := unstructured.NestedString(roleBinding.Object, "roleRef", "kind")
+	name, _, _ := unstructured.NestedString(roleBinding.Object, "roleRef", "name")
+	if kind != "ClusterRole" {
+		return false
+	}
+	switch projectv1.ProjectRole(name) {
+	case projectv1.ProjectRoleAdmin, projectv1.ProjectRoleEdit, projectv1.ProjectRoleView:
+		return true
+	}
+	return false
+}
+
+// validateProjectMembers returns a description of every member that cannot be
+// bound in the namespaces of the project.
+func validateProjectMembers(members []projectv1.ProjectMember) []string {
+	var problems []string
+	for i, member := range members {
+		if member.Name == "" {
+			problems = append(problems, fmt.Sprintf("member %d has no name", i))
+		}
+		if member.Namespace != "" && member.Kind != "ServiceAccount" {
+			problems = append(problems, fmt.Sprintf("member %d is a %s and must not set a namespace", i, member.Kind))
+		}
+	}
+	return problems
+}
+
+// validateDefaultNamespaceQuota returns a description of every resource of
+// the defaultNamespaceQuota with an unknown name or a negative quantity.
+func validateDefaultNamespaceQuota(quota *corev1.ResourceQuotaSpec) []string {
+	if quota == nil {
+		return nil
+	}
+	return validateProjectLimits(quota.Hard)
+}
+
+// validateOvercommit returns a description of every overcommit factor that
+// is not for a limits.* project limit or that is below 1.
+func validateOvercommit(limits corev1.ResourceList, overcommit corev1.ResourceList) []string {
+	one := resource.MustParse("1")
+	var problems []string
+	for _, name := range sortedNames(overcommit) {
+		factor := overcommit[name]
+		if !budget.Overcommittable(name) {
+			problems = append(problems, string(name)+" cannot be overcommitted, only limits.* resources can")
+		} else if _, ok := limits[name]; !ok {
+			problems = append(problems, string(name)+" is not a project limit")
+		}
+		if factor.Cmp(one) < 0 {
+			problems = append(problems, string(name)+" must be at least 1")
+		}
+	}
+	return problems
+}
+
+// allowOrDenyDefaultNamespaceQuota denies a defaultNamespaceQuota that grows
+// beyond what the project has left once the allocated resourceQuotas are
+// accounted for: a new namespace could not get it.
+func allowOrDenyDefaultNamespaceQuota(oldProject *projectv1.Project, project projectv1.Project, allResourceQuotas corev1.ResourceQuotaList) admission.Response {
+	if project.Spec.DefaultNamespaceQuota == nil {
+		return admission.Allowed("project has no defaultNamespaceQuota")
+	}
+
+	var oldHard corev1.ResourceList
+	if oldProject != nil && oldProject.Spec.DefaultNamespaceQuota != nil {
+		oldHard = oldProject.Spec.DefaultNamespaceQuota.Hard
+	}
+	newHard := project.Spec.DefaultNamespaceQuota.Hard
+	quotas := append(append([]corev1.ResourceQuota{}, allResourceQuotas.Items...), corev1.ResourceQuota{Spec: *project.Spec.DefaultNamespaceQuota})
+
+	exceeded := budget.Overrun(budget.Allocatable(project.Spec.ProjectLimits, project.Spec.Overcommit), budget.Overcommitted(quotas, project.Spec.Overcommit), oldHard, newHard)
+	if len(exceeded) == 0 {
+		return admission.Allowed("defaultNamespaceQuota fits in the remaining budget of the project")
+	}
+	return denied(ReasonLimitsExceeded, "defaultNamespaceQuota does not fit in the remaining budget of the project: "+budget.Join(exceeded))
+}
+
+// allowOrDenyLimitsUpdate refuses to lower or add a project limit below what
+// the project's resourceQuotas and children already allocate, unless
+// allowShrink is set.
+// Limits that were already exceeded and are left untouched do not block the
+// update.
+func allowOrDenyLimitsUpdate(oldProject projectv1.Project, project projectv1.Project, allResourceQuotas corev1.ResourceQuotaList, allowShrink bool) admission.Response {
+	oldAllocatable := budget.Allocatable(oldProject.Spec.ProjectLimits, oldProject.Spec.Overcommit)
+	allocatable := budget.Allocatable(project.Spec.ProjectLimits, project.Spec.Overcommit)
+
+	var shrunk []corev1.ResourceName
+	for _, name := range budget.Exceeded(allocatable, budget.Overcommitted(allResourceQuotas.Items, project.Spec.Overcommit)) {
+		oldLimit, ok := oldAllocatable[name]
+		newLimit := allocatable[name]
+		if !ok || newLimit.Cmp(oldLimit) < 0 {
+			shrunk = append(shrunk, name)
+		}
+	}
+
+	if len(shrunk) == 0 {
+		return admission.Allowed("project limits are above the allocated resourceQuotas")
+	}
+	if allowShrink {
+		return admission.Allowed("project limits lowered below the allocated resourceQuotas, the project is over-committed: " + budget.Join(shrunk))
+	}
+	return denied(ReasonLimitsBelowAllocated, "project limits cannot be lowered below the allocated resourceQuotas: "+budget.Join(shrunk))
+}
+
+func sortedNames(list corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(list))
+	for name := range list {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// ProjectValidator implements admission.DecoderInjector.
+// A decoder will be automatically injected.
+
+// InjectDecoder injects the decoder.
+func (v *ProjectValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
@@ -0,0 +1,427 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	projectv1 "project/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("Testing validateProjectLimits function", func() {
+
+	It("Should accept standard, count, storage class and extended resource names", func() {
+		//Given
+		limits := corev1.ResourceList{
+			corev1.ResourceLimitsCPU:                            resource.MustParse("10"),
+			corev1.ResourceRequestsMemory:                       resource.MustParse("10Gi"),
+			corev1.ResourcePods:                                 resource.MustParse("100"),
+			"count/deployments.apps":                            resource.MustParse("10"),
+			"gold.storageclass.storage.k8s.io/requests.storage": resource.MustParse("1Ti"),
+			"nvidia.com/gpu":                                    resource.MustParse("2"),
+			"requests.nvidia.com/gpu":                           resource.MustParse("2"),
+		}
+
+		//When
+		problems := validateProjectLimits(limits)
+
+		//Then
+		Expect(problems).To(BeEmpty())
+	})
+
+	It("Should reject unknown resource names and negative quantities", func() {
+		//Given
+		limits := corev1.ResourceList{
+			"cpus":                   resource.MustParse("10"),
+			corev1.ResourceLimitsCPU: resource.MustParse("-1"),
+		}
+
+		//When
+		problems := validateProjectLimits(limits)
+
+		//Then
+		Expect(problems).To(ConsistOf("unknown resource name cpus", "limits.cpu must not be negative"))
+	})
+})
+
+var _ = Describe("Testing validateOvercommit function", func() {
+
+	limits := corev1.ResourceList{
+		corev1.ResourceLimitsCPU:   resource.MustParse("10"),
+		corev1.ResourceRequestsCPU: resource.MustParse("10"),
+	}
+
+	It("Should accept factors of at least 1 for limits.* project limits", func() {
+		//Given
+		overcommit := corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1.5")}
+
+		//When
+		problems := validateOvercommit(limits, overcommit)
+
+		//Then
+		Expect(problems).To(BeEmpty())
+	})
+
+	It("Should reject requests.* resources, resources without limit and factors below 1", func() {
+		//Given
+		overcommit := corev1.ResourceList{
+			corev1.ResourceRequestsCPU:  resource.MustParse("2"),
+			corev1.ResourceLimitsMemory: resource.MustParse("2"),
+			corev1.ResourceLimitsCPU:    resource.MustParse("0.5"),
+		}
+
+		//When
+		problems := validateOvercommit(limits, overcommit)
+
+		//Then
+		Expect(problems).To(ConsistOf(
+			"requests.cpu cannot be overcommitted, only limits.* resources can",
+			"limits.memory is not a project limit",
+			"limits.cpu must be at least 1",
+		))
+	})
+})
+
+var _ = Describe("Testing validateNamespaceTemplate function", func() {
+
+	It("Should accept named objects without namespace", func() {
+		//Given
+		template := &projectv1.NamespaceTemplate{Objects: []runtime.RawExtension{
+			{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"team"},"data":{"owner":"team"}}`)},
+		}}
+
+		//When
+		problems := validateNamespaceTemplate(template)
+
+		//Then
+		Expect(problems).To(BeEmpty())
+	})
+
+	It("Should reject unnamed and namespaced objects", func() {
+		//Given
+		template := &projectv1.NamespaceTemplate{Objects: []runtime.RawExtension{
+			{Raw: []byte(`{"apiVersion":"v1","kind":"LimitRange","metadata":{}}`)},
+			{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"team","namespace":"test1"}}`)},
+		}}
+
+		//When
+		problems := validateNamespaceTemplate(template)
+
+		//Then
+		Expect(problems).To(ConsistOf("object 0 has no name", "object 1 must not set a namespace"))
+	})
+
+	It("Should reject the kinds a namespace template may not hold", func() {
+		//Given
+		template := &projectv1.NamespaceTemplate{Objects: []runtime.RawExtension{
+			{Raw: []byte(`{"apiVersion":"v1","kind":"ResourceQuota","metadata":{"name":"project-quota"}}`)},
+			{Raw: []byte(`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRoleBinding","metadata":{"name":"admins"}}`)},
+		}}
+
+		//When
+		problems := validateNamespaceTemplate(template)
+
+		//Then
+		Expect(problems).To(ConsistOf(
+			"object 0: kind ResourceQuota of v1 is not allowed in a namespace template",
+			"object 1: kind ClusterRoleBinding of rbac.authorization.k8s.io/v1 is not allowed in a namespace template"))
+	})
+
+	It("Should only let RoleBindings bind the ClusterRoles of the project roles", func() {
+		//Given
+		template := &projectv1.NamespaceTemplate{Objects: []runtime.RawExtension{
+			{Raw: []byte(`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"RoleBinding","metadata":{"name":"viewers"},"roleRef":{"apiGroup":"rbac.authorization.k8s.io","kind":"ClusterRole","name":"view"}}`)},
+			{Raw: []byte(`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"RoleBinding","metadata":{"name":"root"},"roleRef":{"apiGroup":"rbac.authorization.k8s.io","kind":"ClusterRole","name":"cluster-admin"}}`)},
+		}}
+
+		//When
+		problems := validateNamespaceTemplate(template)
+
+		//Then
+		Expect(problems).To(ConsistOf("object 1 must bind the admin, edit or view ClusterRole"))
+	})
+})
+
+var _ = Describe("Testing validateProjectMembers function", func() {
+
+	It("Should only let service accounts set a namespace", func() {
+		//Given
+		members := []projectv1.ProjectMember{
+			{Kind: "ServiceAccount", Name: "deployer", Namespace: "ci", Role: projectv1.ProjectRoleEdit},
+			{Kind: "User", Name: "alice", Namespace: "ci", Role: projectv1.ProjectRoleView},
+			{Kind: "Group", Role: projectv1.ProjectRoleAdmin},
+		}
+
+		//When
+		problems := validateProjectMembers(members)
+
+		//Then
+		Expect(problems).To(ConsistOf("member 1 is a User and must not set a namespace", "member 2 has no name"))
+	})
+})
+
+var _ = Describe("Testing allowOrDenyLimitsUpdate function", func() {
+
+	quota1 := setResourceQuota(10, 1000)
+	quota2 := setResourceQuota(80, 8000)
+	allResourceQuotas := fillResourcequotaList(quota1, quota2)
+
+	It("Should allow to lower the project's limits down to the allocated resourceQuotas", func() {
+		//Given
+		oldProject := setProject(100, 10000)
+		project := setProject(90, 9000)
+
+		reason := metav1.StatusReason("project limits are above the allocated resourceQuotas")
+
+		//When
+		result := allowOrDenyLimitsUpdate(oldProject, project, allResourceQuotas, false)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.Result.Reason).To(Equal(reason))
+	})
+
+	It("Should deny to lower the project's limits below the allocated resourceQuotas", func() {
+		//Given
+		oldProject := setProject(100, 10000)
+		project := setProject(89, 10000)
+
+		message := "project limits cannot be lowered below the allocated resourceQuotas: limits.cpu"
+
+		//When
+		result := allowOrDenyLimitsUpdate(oldProject, project, allResourceQuotas, false)
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonLimitsBelowAllocated))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should deny to add a project limit below the allocated resourceQuotas", func() {
+		//Given
+		oldProject := setProject(100, 10000)
+		project := setProject(100, 10000)
+		project.Spec.ProjectLimits[corev1.ResourceRequestsCPU] = resource.MustParse("50")
+
+		message := "project limits cannot be lowered below the allocated resourceQuotas: requests.cpu"
+
+		//When
+		result := allowOrDenyLimitsUpdate(oldProject, project, allResourceQuotas, false)
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonLimitsBelowAllocated))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should allow to lower the project's limits below the allocated resourceQuotas when the policy allows it", func() {
+		//Given
+		oldProject := setProject(100, 10000)
+		project := setProject(50, 10000)
+
+		reason := metav1.StatusReason("project limits lowered below the allocated resourceQuotas, the project is over-committed: limits.cpu")
+
+		//When
+		result := allowOrDenyLimitsUpdate(oldProject, project, allResourceQuotas, true)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.Result.Reason).To(Equal(reason))
+	})
+
+	It("Should allow to lower the project's limits when the overcommit still covers the allocated resourceQuotas", func() {
+		//Given
+		oldProject := setProjectLimits(corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("100")})
+		project := setProjectLimits(corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("50")})
+		project.Spec.Overcommit = corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2")}
+		quotas := fillResourcequotaList(setResourceQuotaHard(corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("90")}))
+
+		//When
+		result := allowOrDenyLimitsUpdate(oldProject, project, quotas, false)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+
+	It("Should allow unrelated updates of a project that is already over-committed", func() {
+		//Given
+		oldProject := setProject(50, 10000)
+		project := setProject(50, 20000)
+
+		reason := metav1.StatusReason("project limits are above the allocated resourceQuotas")
+
+		//When
+		result := allowOrDenyLimitsUpdate(oldProject, project, allResourceQuotas, false)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.Result.Reason).To(Equal(reason))
+	})
+})
+
+var _ = Describe("Testing allowOrDenyDefaultNamespaceQuota function", func() {
+
+	allResourceQuotas := fillResourcequotaList(setResourceQuota(60, 6000))
+
+	It("Should allow a defaultNamespaceQuota that fits in the remaining budget", func() {
+		//Given
+		project := setProject(100, 10000)
+		quota := setResourceQuota(40, 4000)
+		project.Spec.DefaultNamespaceQuota = &quota.Spec
+
+		//When
+		result := allowOrDenyDefaultNamespaceQuota(nil, project, allResourceQuotas)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+
+	It("Should deny a defaultNamespaceQuota growing beyond the remaining budget", func() {
+		//Given
+		oldProject := setProject(100, 10000)
+		oldQuota := setResourceQuota(40, 4000)
+		oldProject.Spec.DefaultNamespaceQuota = &oldQuota.Spec
+		project := setProject(100, 10000)
+		quota := setResourceQuota(41, 4000)
+		project.Spec.DefaultNamespaceQuota = &quota.Spec
+
+		message := "defaultNamespaceQuota does not fit in the remaining budget of the project: limits.cpu"
+
+		//When
+		result := allowOrDenyDefaultNamespaceQuota(&oldProject, project, allResourceQuotas)
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should not block an update leaving an already short defaultNamespaceQuota untouched", func() {
+		//Given
+		project := setProject(100, 10000)
+		quota := setResourceQuota(50, 4000)
+		project.Spec.DefaultNamespaceQuota = &quota.Spec
+
+		//When
+		result := allowOrDenyDefaultNamespaceQuota(&project, project, allResourceQuotas)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+})
+
+var _ = Describe("Testing ProjectValidator", func() {
+
+	newValidator := func(objects ...runtime.Object) *ProjectValidator {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(projectv1.AddToScheme(scheme)).To(Succeed())
+		decoder, err := admission.NewDecoder(scheme)
+		Expect(err).NotTo(HaveOccurred())
+		validator := &ProjectValidator{Client: indexedClient{fake.NewFakeClientWithScheme(scheme, objects...)}}
+		Expect(validator.InjectDecoder(decoder)).To(Succeed())
+		return validator
+	}
+
+	projectUpdateRequest := func(oldProject projectv1.Project, project projectv1.Project) admission.Request {
+		raw, err := json.Marshal(project)
+		Expect(err).NotTo(HaveOccurred())
+		oldRaw, err := json.Marshal(oldProject)
+		Expect(err).NotTo(HaveOccurred())
+		return admission.Request{AdmissionRequest: v1beta1.AdmissionRequest{
+			Operation: v1beta1.Update,
+			Name:      project.Name,
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		}}
+	}
+
+	child := projectv1.Project{
+		ObjectMeta: metav1.ObjectMeta{Name: "child"},
+		Spec: projectv1.ProjectSpec{
+			Parent:        "parent",
+			ProjectLimits: corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("2")},
+		},
+	}
+
+	It("Should allow adding the finalizer of a child project whose parent is gone", func() {
+		//Given
+		validator := newValidator(child.DeepCopy())
+		finalized := *child.DeepCopy()
+		finalized.Finalizers = []string{projectv1.ProjectFinalizer}
+
+		//When
+		result := validator.Handle(context.Background(), projectUpdateRequest(child, finalized))
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+
+	It("Should allow updating a deleted child project whose parent is gone", func() {
+		//Given
+		validator := newValidator(child.DeepCopy())
+		now := metav1.Now()
+		deleted := *child.DeepCopy()
+		deleted.DeletionTimestamp = &now
+		deleted.Finalizers = []string{projectv1.ProjectFinalizer}
+		released := *deleted.DeepCopy()
+		released.Finalizers = nil
+
+		//When
+		result := validator.Handle(context.Background(), projectUpdateRequest(deleted, released))
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+
+	It("Should deny raising the limits of a child project whose parent is gone", func() {
+		//Given
+		validator := newValidator(child.DeepCopy())
+		raised := *child.DeepCopy()
+		raised.Spec.ProjectLimits = corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("3")}
+
+		//When
+		result := validator.Handle(context.Background(), projectUpdateRequest(child, raised))
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonProjectNotFound))
+		Expect(result.Result.Message).To(Equal("parent project parent does not exist"))
+	})
+
+	It("Should deny deleting a project that has children", func() {
+		//Given
+		parent := &projectv1.Project{ObjectMeta: metav1.ObjectMeta{Name: "parent"}}
+		validator := newValidator(parent, child.DeepCopy())
+		request := admission.Request{AdmissionRequest: v1beta1.AdmissionRequest{Operation: v1beta1.Delete, Name: "parent"}}
+
+		//When
+		result := validator.Handle(context.Background(), request)
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonHasChildren))
+		Expect(result.Result.Message).To(Equal("project has children, detach or delete them first: child"))
+	})
+
+	It("Should allow deleting a project without children", func() {
+		//Given
+		validator := newValidator(child.DeepCopy())
+		request := admission.Request{AdmissionRequest: v1beta1.AdmissionRequest{Operation: v1beta1.Delete, Name: "child"}}
+
+		//When
+		result := validator.Handle(context.Background(), request)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+})
@@ -0,0 +1,179 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"net/http"
+	projectv1 "project/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/mutate-v1-quotachangerequest,mutating=true,failurePolicy=fail,groups=project.my.domain,resources=quotachangerequests,verbs=create;update,versions=v1,name=mquotachangerequest.kb.io
+
+// QuotaChangeRequestAdmission records who requested and who decided on a
+// QuotaChangeRequest, and only lets project admins decide
+type QuotaChangeRequestAdmission struct {
+	Client client.Client
+	// Names are the project label and the project-quota name
+	Names   projectv1.Names
+	decoder *admission.Decoder
+}
+
+// quotaChangeRequest admission
+func (a *QuotaChangeRequestAdmission) Handle(ctx context.Context, req admission.Request) admission.Response {
+	switch req.Operation {
+	case v1beta1.Create:
+		return a.admitCreate(ctx, req)
+	case v1beta1.Update:
+		return a.admitUpdate(ctx, req)
+	default:
+		return admission.Allowed("No specific logic for" + string(req.Operation) + " operations")
+	}
+}
+
+func (a *QuotaChangeRequestAdmission) admitCreate(ctx context.Context, req admission.Request) admission.Response {
+	request := projectv1.QuotaChangeRequest{}
+	err := a.decoder.Decode(req, &request)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	setAnnotation(&request, projectv1.QuotaChangeRequestedByAnnotation, req.UserInfo.Username)
+	delete(request.Annotations, projectv1.QuotaChangeDecidedByAnnotation)
+	if request.Spec.Decision != "" {
+		if response := a.allowOrDenyDecision(ctx, request, req.UserInfo); !response.Allowed {
+			return response
+		}
+		setAnnotation(&request, projectv1.QuotaChangeDecidedByAnnotation, req.UserInfo.Username)
+	}
+	return patchResponse(req, request)
+}
+
+func (a *QuotaChangeRequestAdmission) admitUpdate(ctx context.Context, req admission.Request) admission.Response {
+	request := projectv1.QuotaChangeRequest{}
+	oldRequest := projectv1.QuotaChangeRequest{}
+	err := a.decoder.Decode(req, &request)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	err = a.decoder.DecodeRaw(req.OldObject, &oldRequest)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if response := allowOrDenyQuotaChangeUpdate(oldRequest, request); !response.Allowed {
+		return response
+	}
+
+	// The recorded users cannot be changed
+	setAnnotation(&request, projectv1.QuotaChangeRequestedByAnnotation, oldRequest.Annotations[projectv1.QuotaChangeRequestedByAnnotation])
+	setAnnotation(&request, projectv1.QuotaChangeDecidedByAnnotation, oldRequest.Annotations[projectv1.QuotaChangeDecidedByAnnotation])
+	if request.Spec.Decision != oldRequest.Spec.Decision {
+		if response := a.allowOrDenyDecision(ctx, request, req.UserInfo); !response.Allowed {
+			return response
+		}
+		setAnnotation(&request, projectv1.QuotaChangeDecidedByAnnotation, req.UserInfo.Username)
+	}
+	return patchResponse(req, request)
+}
+
+// allowOrDenyQuotaChangeUpdate keeps what was requested and decided as it
+// was, for audit.
+func allowOrDenyQuotaChangeUpdate(oldRequest projectv1.QuotaChangeRequest, request projectv1.QuotaChangeRequest) admission.Response {
+	if !equality.Semantic.DeepEqual(oldRequest.Spec.Delta, request.Spec.Delta) || oldRequest.Spec.Reason != request.Spec.Reason {
+		return denied(ReasonImmutable, "the delta and the reason of a QuotaChangeRequest cannot be changed")
+	}
+	if oldRequest.Spec.Decision != "" && request.Spec.Decision != oldRequest.Spec.Decision {
+		return denied(ReasonImmutable, "the decision on a QuotaChangeRequest cannot be changed")
+	}
+	return admission.Allowed("QuotaChangeRequest update keeps its request and decision")
+}
+
+// allowOrDenyDecision only lets the admins of the project of the request decide on it.
+func (a *QuotaChangeRequestAdmission) allowOrDenyDecision(ctx context.Context, request projectv1.QuotaChangeRequest, userInfo authenticationv1.UserInfo) admission.Response {
+	project, err := projectOfNamespace(ctx, a.Client, a.Names, request.Namespace)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if project == nil || !isProjectAdmin(*project, request.Namespace, userInfo) {
+		return denied(ReasonUnauthorized, "only an admin of the project can decide on a QuotaChangeRequest")
+	}
+	return admission.Allowed("user is an admin of the project")
+}
+
+// isProjectAdmin reports whether the user is a member of the project with the
+// admin role. A ServiceAccount member without namespace is looked for in the
+// given namespace.
+func isProjectAdmin(project projectv1.Project, namespace string, userInfo authenticationv1.UserInfo) bool {
+	for _, member := range project.Spec.Members {
+		if member.Role != projectv1.ProjectRoleAdmin {
+			continue
+		}
+		switch member.Kind {
+		case "User":
+			if member.Name == userInfo.Username {
+				return true
+			}
+		case "Group":
+			for _, group := range userInfo.Groups {
+				if member.Name == group {
+					return true
+				}
+			}
+		case "ServiceAccount":
+			memberNamespace := member.Namespace
+			if memberNamespace == "" {
+				memberNamespace = namespace
+			}
+			if "system:serviceaccount:"+memberNamespace+":"+member.Name == userInfo.Username {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func setAnnotation(request *projectv1.QuotaChangeRequest, key string, value string) {
+	if value == "" {
+		delete(request.Annotations, key)
+		return
+	}
+	if request.Annotations == nil {
+		request.Annotations = map[string]string{}
+	}
+	request.Annotations[key] = value
+}
+
+func patchResponse(req admission.Request, request projectv1.QuotaChangeRequest) admission.Response {
+	marshaled, err := json.Marshal(request)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// QuotaChangeRequestAdmission implements admission.DecoderInjector.
+// A decoder will be automatically injected.
+
+// InjectDecoder injects the decoder.
+func (a *QuotaChangeRequestAdmission) InjectDecoder(d *admission.Decoder) error {
+	a.decoder = d
+	return nil
+}
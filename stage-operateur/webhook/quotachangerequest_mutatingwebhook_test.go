@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	projectv1 "project/api/v1"
+)
+
+var _ = Describe("Testing isProjectAdmin function", func() {
+
+	project := setProject(10, 1000)
+	project.Spec.Members = []projectv1.ProjectMember{
+		{Kind: "User", Name: "alice", Role: projectv1.ProjectRoleAdmin},
+		{Kind: "User", Name: "bob", Role: projectv1.ProjectRoleEdit},
+		{Kind: "Group", Name: "team-leads", Role: projectv1.ProjectRoleAdmin},
+		{Kind: "ServiceAccount", Name: "approver", Role: projectv1.ProjectRoleAdmin},
+	}
+
+	It("Should recognize admin users, groups and service accounts", func() {
+		Expect(isProjectAdmin(project, "test1", authenticationv1.UserInfo{Username: "alice"})).To(BeTrue())
+		Expect(isProjectAdmin(project, "test1", authenticationv1.UserInfo{Username: "carol", Groups: []string{"team-leads"}})).To(BeTrue())
+		Expect(isProjectAdmin(project, "test1", authenticationv1.UserInfo{Username: "system:serviceaccount:test1:approver"})).To(BeTrue())
+	})
+
+	It("Should not recognize other members", func() {
+		Expect(isProjectAdmin(project, "test1", authenticationv1.UserInfo{Username: "bob"})).To(BeFalse())
+		Expect(isProjectAdmin(project, "test1", authenticationv1.UserInfo{Username: "system:serviceaccount:test2:approver"})).To(BeFalse())
+	})
+})
+
+var _ = Describe("Testing allowOrDenyQuotaChangeUpdate function", func() {
+
+	newRequest := func(cpu string, decision projectv1.QuotaChangeDecision) projectv1.QuotaChangeRequest {
+		return projectv1.QuotaChangeRequest{Spec: projectv1.QuotaChangeRequestSpec{
+			Delta:    corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse(cpu)},
+			Reason:   "load test",
+			Decision: decision,
+		}}
+	}
+
+	It("Should allow a decision on a pending request", func() {
+		//When
+		result := allowOrDenyQuotaChangeUpdate(newRequest("1", ""), newRequest("1", projectv1.QuotaChangeDecisionApproved))
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+
+	It("Should deny a change of the delta", func() {
+		//Given
+		message := "the delta and the reason of a QuotaChangeRequest cannot be changed"
+
+		//When
+		result := allowOrDenyQuotaChangeUpdate(newRequest("1", ""), newRequest("2", ""))
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonImmutable))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+
+	It("Should deny a change of the decision", func() {
+		//When
+		result := allowOrDenyQuotaChangeUpdate(newRequest("1", projectv1.QuotaChangeDecisionRejected), newRequest("1", projectv1.QuotaChangeDecisionApproved))
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+	})
+})
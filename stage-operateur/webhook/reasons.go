@@ -0,0 +1,64 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// The reasons of the denials of the webhooks, set in Result.Reason where the
+// admission metrics count them; Result.Message tells the details.
+const (
+	// ReasonLimitsExceeded is a request pushing a project over its limits
+	ReasonLimitsExceeded metav1.StatusReason = "LimitsExceeded"
+	// ReasonAncestorLimits is a request pushing an ancestor project over its limits
+	ReasonAncestorLimits metav1.StatusReason = "AncestorLimits"
+	// ReasonParentLimits is a child project whose limits do not fit in its parent
+	ReasonParentLimits metav1.StatusReason = "ParentLimits"
+	// ReasonClusterBudgetExceeded is a project whose limits do not fit in a ClusterBudget
+	ReasonClusterBudgetExceeded metav1.StatusReason = "ClusterBudgetExceeded"
+	// ReasonLimitsBelowAllocated is a project whose limits are lowered below
+	// its allocated resourceQuotas
+	ReasonLimitsBelowAllocated metav1.StatusReason = "LimitsBelowAllocated"
+	// ReasonUnauthorized is a user who may not make the change
+	ReasonUnauthorized metav1.StatusReason = "Unauthorized"
+	// ReasonProjectNotFound is a reference to a project that does not exist
+	ReasonProjectNotFound metav1.StatusReason = "ProjectNotFound"
+	// ReasonForeignQuota is a resourceQuota other than the project-quota in an
+	// Exclusive project namespace
+	ReasonForeignQuota metav1.StatusReason = "ForeignQuota"
+	// ReasonInvalidSpec is a project spec that is not valid
+	ReasonInvalidSpec metav1.StatusReason = "InvalidSpec"
+	// ReasonInvalidParent is a parent that would make a cycle of projects
+	ReasonInvalidParent metav1.StatusReason = "InvalidParent"
+	// ReasonHasChildren is a project deleted while it has children
+	ReasonHasChildren metav1.StatusReason = "HasChildren"
+	// ReasonImmutable is a change to a field that cannot be changed
+	ReasonImmutable metav1.StatusReason = "Immutable"
+	// ReasonQuotaProtected is a project-quota deleted while its namespace is
+	// not terminating
+	ReasonQuotaProtected metav1.StatusReason = "QuotaProtected"
+)
+
+// denied denies the request for the reason, the message telling why.
+func denied(reason metav1.StatusReason, message string) admission.Response {
+	response := admission.Denied(message)
+	response.Result.Reason = reason
+	response.Result.Message = message
+	return response
+}
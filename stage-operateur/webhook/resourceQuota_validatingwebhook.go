@@ -17,18 +17,28 @@ import (
 	"context"
 	"k8s.io/api/admission/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
 	"net/http"
 	projectv1 "project/api/v1"
+	"project/budget"
+	"project/index"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
-// +kubebuilder:webhook:path=/validate-v1-resourcequota,mutating=false,failurePolicy=fail,groups="",resources=resourcequotas,verbs=update;delete,versions=v1,name=vresourcequota.kb.io
+// +kubebuilder:webhook:path=/validate-v1-resourcequota,mutating=false,failurePolicy=fail,groups="",resources=resourcequotas,verbs=create;update;delete,versions=v1,name=vresourcequota.kb.io
 
 // resourceQuotaValidator validates ResourceQuotas
 type ResourceQuotaValidator struct {
-	Client  client.Client
-	decoder *admission.Decoder
+	Client client.Client
+	// AccountingMode selects the ResourceQuotas counted against the project limits
+	AccountingMode budget.AccountingMode
+	// Names are the project label and the project-quota name
+	Names projectv1.Names
+	// Recorder, when set, emits an Event on the project of a denied resourceQuota
+	Recorder record.EventRecorder
+	decoder  *admission.Decoder
 }
 
 // resourceQuota validator
@@ -46,58 +56,54 @@ func (v *ResourceQuotaValidator) Handle(ctx context.Context, req admission.Reque
 }
 
 func (v *ResourceQuotaValidator) validateCreate(ctx context.Context, req admission.Request) admission.Response {
-	namespace := corev1.Namespace{}
-	err := v.Client.Get(ctx, client.ObjectKey{Name: req.Namespace}, &namespace)
+	project, err := projectOfNamespace(ctx, v.Client, v.Names, req.Namespace)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
-	project := projectv1.Project{}
-	err = v.Client.Get(ctx, client.ObjectKey{Name: namespace.Labels["project"]}, &project)
-	if err != nil {
-		return admission.Errored(http.StatusInternalServerError, err)
+	if project == nil {
+		return admission.Allowed("resourceQuota not related to project")
 	}
 
 	quota := corev1.ResourceQuota{}
-	oldQuota := &corev1.ResourceQuota{}
-
 	err = v.decoder.Decode(req, &quota)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	err = v.decoder.DecodeRaw(req.OldObject, oldQuota)
-	if err != nil {
-		return admission.Errored(http.StatusInternalServerError, err)
+	if !v.AccountingMode.Counts(v.Names, quota.Name) {
+		return allowOrDenyUncounted(v.AccountingMode)
 	}
 
-	namespaceList := corev1.NamespaceList{}
-	err = v.Client.List(ctx, &namespaceList)
+	resourceQuotaList, err := allResourceQuotasInProject(ctx, v.Client, *project, v.AccountingMode, v.Names)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	resourceQuotaList, err := v.allResourceQuotasInProject(ctx, project)
-	if err != nil {
-		return admission.Errored(http.StatusInternalServerError, err)
+	// In AllQuotas mode the namespace is bound by the tightest of its
+	// quotas: a new quota is checked as the change it makes to that
+	// effective quota, not as an increase from nothing.
+	counted, oldQuota := quota, (*corev1.ResourceQuota)(nil)
+	if v.AccountingMode == budget.AllQuotas {
+		counted, oldQuota = effectiveChange(resourceQuotaList, quota)
 	}
 
-	return allowOrDenyUpdateOrCreate(project, quota, oldQuota, resourceQuotaList)
+	response := allowOrDenyUpdateOrCreate(*project, counted, oldQuota, perNamespace(withResourceQuota(resourceQuotaList, counted)))
+	if response.Allowed {
+		response = allowOrDenyInAncestors(ctx, v.Client, *project, counted, oldQuota, v.AccountingMode, v.Names, response)
+	}
+	v.recordDenied(req, project, response)
+	return response
 }
-//TODO resourcequota du projet seulement
+
 func (v *ResourceQuotaValidator) validateUpdate(ctx context.Context, req admission.Request) admission.Response {
-	namespace := corev1.Namespace{}
-	err := v.Client.Get(ctx, client.ObjectKey{Name: req.Namespace}, &namespace)
+	project, err := projectOfNamespace(ctx, v.Client, v.Names, req.Namespace)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
-
-	project := projectv1.Project{}
-	err = v.Client.Get(ctx, client.ObjectKey{Name: namespace.Labels["project"]}, &project)
-	if err != nil {
-		return admission.Errored(http.StatusInternalServerError, err)
+	if project == nil {
+		return admission.Allowed("resourceQuota not related to project")
 	}
 
-
 	quota := corev1.ResourceQuota{}
 	oldQuota := &corev1.ResourceQuota{}
 	err = v.decoder.Decode(req, &quota)
@@ -110,12 +116,91 @@ func (v *ResourceQuotaValidator) validateUpdate(ctx context.Context, req admissi
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	resourceQuotaList, err := v.allResourceQuotasInProject(ctx, project)
+	if !v.AccountingMode.Counts(v.Names, quota.Name) {
+		return allowOrDenyUncounted(v.AccountingMode)
+	}
+
+	resourceQuotaList, err := allResourceQuotasInProject(ctx, v.Client, *project, v.AccountingMode, v.Names)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	return allowOrDenyUpdateOrCreate(project, quota, oldQuota, resourceQuotaList)
+	response := allowOrDenyUpdateOrCreate(*project, quota, oldQuota, perNamespace(withResourceQuota(resourceQuotaList, quota)))
+	if response.Allowed {
+		response = allowOrDenyInAncestors(ctx, v.Client, *project, quota, oldQuota, v.AccountingMode, v.Names, response)
+	}
+	v.recordDenied(req, project, response)
+	return response
+}
+
+// recordDenied emits an Event on the project of a denied resourceQuota, so
+// that its owners see why with kubectl describe project. Dry runs are not
+// recorded.
+func (v *ResourceQuotaValidator) recordDenied(req admission.Request, project *projectv1.Project, response admission.Response) {
+	if response.Allowed || v.Recorder == nil || (req.DryRun != nil && *req.DryRun) {
+		return
+	}
+	v.Recorder.Event(project, corev1.EventTypeWarning, "QuotaDenied",
+		"resourceQuota "+req.Namespace+"/"+req.Name+" denied: "+response.Result.Message)
+}
+
+// projectOfNamespace returns the project the namespace is labelled with, or
+// nil when the namespace does not belong to an existing project.
+func projectOfNamespace(ctx context.Context, c client.Client, names projectv1.Names, namespaceName string) (*projectv1.Project, error) {
+	namespace := corev1.Namespace{}
+	err := c.Get(ctx, client.ObjectKey{Name: namespaceName}, &namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	projectName, ok := namespace.Labels[names.Label()]
+	if !ok {
+		return nil, nil
+	}
+
+	project := projectv1.Project{}
+	err = c.Get(ctx, client.ObjectKey{Name: projectName}, &project)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// withResourceQuota returns the project's resourceQuotas as they will be once
+// quota is stored: it replaces the stored version of quota, or is added to
+// the list when it is created.
+func withResourceQuota(resourceQuotaList corev1.ResourceQuotaList, quota corev1.ResourceQuota) corev1.ResourceQuotaList {
+	result := corev1.ResourceQuotaList{Items: make([]corev1.ResourceQuota, 0, len(resourceQuotaList.Items)+1)}
+	for _, resourceQuota := range resourceQuotaList.Items {
+		if resourceQuota.Namespace == quota.Namespace && resourceQuota.Name == quota.Name {
+			continue
+		}
+		result.Items = append(result.Items, resourceQuota)
+	}
+	result.Items = append(result.Items, quota)
+	return result
+}
+
+// effectiveChange returns the created quota with the spec.hard its namespace
+// is bound by once it is stored, the tightest of its quotas, and the
+// namespace's effective quota before, nil when it has no other quota.
+func effectiveChange(resourceQuotaList corev1.ResourceQuotaList, quota corev1.ResourceQuota) (corev1.ResourceQuota, *corev1.ResourceQuota) {
+	var current []corev1.ResourceQuota
+	for _, resourceQuota := range resourceQuotaList.Items {
+		if resourceQuota.Namespace == quota.Namespace && resourceQuota.Name != quota.Name {
+			current = append(current, resourceQuota)
+		}
+	}
+	if len(current) == 0 {
+		return quota, nil
+	}
+	before := budget.Effective(current)
+	counted := *quota.DeepCopy()
+	counted.Spec.Hard = budget.Effective(append(current, quota)).Spec.Hard
+	return counted, &before
 }
 
 func (v *ResourceQuotaValidator) validateDelete(ctx context.Context, req admission.Request) admission.Response {
@@ -125,72 +210,113 @@ func (v *ResourceQuotaValidator) validateDelete(ctx context.Context, req admissi
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	project := projectv1.Project{}
-	err = v.Client.Get(ctx, client.ObjectKey{Name: namespace.Labels["project"]}, &project)
+	project, err := projectOfNamespace(ctx, v.Client, v.Names, req.Namespace)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
+	if project == nil {
+		return admission.Allowed("resourceQuota not related to project")
+	}
 
 	quota := corev1.ResourceQuota{}
-	err = v.decoder.Decode(req, &quota)
+	err = v.decoder.DecodeRaw(req.OldObject, &quota)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	if quota.Name == "project-quota" && namespace.Labels["project"] == project.Name {
-		return allowOrDenyDelete(namespace)
+	if quota.Name != v.Names.QuotaName() {
+		return admission.Allowed("resourceQuota not related to project")
+	}
+	if project.DeletionTimestamp != nil {
+		return admission.Allowed("Project being deleted")
 	}
-	return admission.Allowed("resourceQuota not related to project")
+	return allowOrDenyDelete(namespace)
 }
 
-func (v *ResourceQuotaValidator) allResourceQuotasInProject( ctx context.Context, project projectv1.Project) (ResourceQuotaList corev1.ResourceQuotaList, err error) {
-	namespaceList := corev1.NamespaceList{}
-	err = v.Client.List(ctx, &namespaceList)
-
+// allResourceQuotasInProject returns the counted ResourceQuotas of the
+// namespaces of the project. The namespaces are found through the field index
+// index.NamespaceProject and their quotas are read from the cache, so the
+// cost does not grow with the number of namespaces of the cluster.
+func allResourceQuotasInProject(ctx context.Context, c client.Client, project projectv1.Project, mode budget.AccountingMode, names projectv1.Names) (corev1.ResourceQuotaList, error) {
 	resourceQuotaList := corev1.ResourceQuotaList{}
-	var resourceQuota corev1.ResourceQuota
+	namespaceList := corev1.NamespaceList{}
+	if err := c.List(ctx, &namespaceList, client.MatchingFields{index.NamespaceProject: project.Name}); err != nil {
+		return resourceQuotaList, err
+	}
 
 	for _, namespace := range namespaceList.Items {
-		if namespace.Labels["project"] == project.Name {
-
-			err = v.Client.Get(ctx, client.ObjectKey{Name:"project-quota", Namespace: namespace.Name}, &resourceQuota)
+		quotas, err := namespaceResourceQuotas(ctx, c, namespace.Name, mode, names)
+		if err != nil {
+			return resourceQuotaList, err
+		}
+		resourceQuotaList.Items = append(resourceQuotaList.Items, quotas...)
+	}
+	return resourceQuotaList, nil
+}
 
-			resourceQuotaList.Items = append(resourceQuotaList.Items, resourceQuota)
+// namespaceResourceQuotas returns the ResourceQuotas of one namespace that the
+// accounting mode counts against the project limits.
+func namespaceResourceQuotas(ctx context.Context, c client.Client, namespaceName string, mode budget.AccountingMode, names projectv1.Names) ([]corev1.ResourceQuota, error) {
+	if mode == budget.AllQuotas {
+		resourceQuotaList := corev1.ResourceQuotaList{}
+		err := c.List(ctx, &resourceQuotaList, client.InNamespace(namespaceName))
+		return resourceQuotaList.Items, err
+	}
 
-		}
+	resourceQuota := corev1.ResourceQuota{}
+	err := c.Get(ctx, client.ObjectKey{Name: names.QuotaName(), Namespace: namespaceName}, &resourceQuota)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
-	return resourceQuotaList, err
+	return []corev1.ResourceQuota{resourceQuota}, nil
 }
 
-func allowOrDenyUpdateOrCreate(project projectv1.Project, quota corev1.ResourceQuota, oldQuota *corev1.ResourceQuota, allResourceQuotas corev1.ResourceQuotaList) admission.Response {
+// perNamespace keeps one quota per namespace, the tightest of its counted
+// ResourceQuotas.
+func perNamespace(resourceQuotaList corev1.ResourceQuotaList) corev1.ResourceQuotaList {
+	return corev1.ResourceQuotaList{Items: budget.PerNamespace(resourceQuotaList.Items)}
+}
 
+// allowOrDenyUncounted handles a ResourceQuota that the accounting mode does
+// not count against the project limits.
+func allowOrDenyUncounted(mode budget.AccountingMode) admission.Response {
+	if mode == budget.Exclusive {
+		return denied(ReasonForeignQuota, "only the project-quota resourceQuota is allowed in the namespaces of a project")
+	}
+	return admission.Allowed("resourceQuota not counted against the project")
+}
 
-	projectCpuLimit := project.Spec.ProjectLimits[corev1.ResourceLimitsCPU]
-	projectMemoryLimit := project.Spec.ProjectLimits[corev1.ResourceLimitsMemory]
+func allowOrDenyUpdateOrCreate(project projectv1.Project, quota corev1.ResourceQuota, oldQuota *corev1.ResourceQuota, allResourceQuotas corev1.ResourceQuotaList) admission.Response {
 
-	if oldQuota == nil  {
-		return admission.Allowed("allow creation of resourceQuota, by default it does not increase cpu or memory usage in the project")
-	} else if oldQuota.Spec.Hard.Cpu().Value() >= quota.Spec.Hard.Cpu().Value() &&
-		oldQuota.Spec.Hard.Memory().Value() >= quota.Spec.Hard.Memory().Value(){
-		return admission.Allowed("resourceQuota cpu and memory can be decreased no matter the limits")
-	} else {
-		var SumRQCpu int64 = 0
-		var SumRQMemory int64 = 0
+	projectLimits := budget.Allocatable(project.Spec.ProjectLimits, project.Spec.Overcommit)
 
-		for _, resourceQuota := range allResourceQuotas.Items  {
+	// A created resourceQuota is counted against the project's limits like
+	// an increase from an empty resourceQuota.
+	oldHard := corev1.ResourceList{}
+	if oldQuota != nil {
+		oldHard = oldQuota.Spec.Hard
+	}
 
-			SumRQCpu += resourceQuota.Spec.Hard.Cpu().Value()
-			SumRQMemory += resourceQuota.Spec.Hard.Memory().Value()
-		}
+	increased := budget.Increased(projectLimits, oldHard, quota.Spec.Hard)
+	if len(increased) == 0 && oldQuota == nil {
+		return admission.Allowed("allow creation of resourceQuota, it does not increase resource usage in the project")
+	}
+	if len(increased) == 0 {
+		return admission.Allowed("resourceQuota does not increase any resource limited by the project")
+	}
 
-		if SumRQCpu <= projectCpuLimit.Value() &&
-			SumRQMemory <= projectMemoryLimit.Value() {
-			return admission.Allowed("sum of resourceQuotas memory and cpu limits below project's limits, allow resourceQuota update")
-		} else {
-			return admission.Denied("resourceQuota cpu or memory increase is forbidden when project limits have been exceeded")
-		}
+	// Every resource named in the project limits is capped across all the
+	// project's namespaces, but only the ones this update increases can be
+	// refused: a project that is already over a limit can still shrink.
+	exceeded := budget.Overrun(projectLimits, budget.Overcommitted(allResourceQuotas.Items, project.Spec.Overcommit), oldHard, quota.Spec.Hard)
 
+	if len(exceeded) == 0 {
+		return admission.Allowed("sum of resourceQuotas below project's limits, allow resourceQuota update")
 	}
+	return denied(ReasonLimitsExceeded, "resourceQuota increase is forbidden when project limits have been exceeded: "+budget.Join(exceeded))
 }
 
 func allowOrDenyDelete(namespace corev1.Namespace) admission.Response {
@@ -198,7 +324,7 @@ func allowOrDenyDelete(namespace corev1.Namespace) admission.Response {
 	if namespace.DeletionTimestamp != nil {
 		return admission.Allowed("Namespace terminating")
 	}
-	return admission.Denied("Namespace not terminating")
+	return denied(ReasonQuotaProtected, "Namespace not terminating")
 }
 
 // resourceQuotaValidator implements admission.DecoderInjector.
@@ -4,9 +4,13 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/admission/v1beta1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	projectv1 "project/api/v1"
+	"project/budget"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"testing"
 )
 
@@ -41,6 +45,32 @@ func setProject(cpuLimit int64, memoryLimit int64) projectv1.Project {
 	return project
 }
 
+func setResourceQuotaFromStrings(cpu string, memory string) corev1.ResourceQuota {
+	quota := setResourceQuota(0, 0)
+	quota.Spec.Hard[corev1.ResourceCPU] = resource.MustParse(cpu)
+	quota.Spec.Hard[corev1.ResourceMemory] = resource.MustParse(memory)
+	return quota
+}
+
+func setProjectFromStrings(cpuLimit string, memoryLimit string) projectv1.Project {
+	project := setProject(0, 0)
+	project.Spec.ProjectLimits[corev1.ResourceLimitsCPU] = resource.MustParse(cpuLimit)
+	project.Spec.ProjectLimits[corev1.ResourceLimitsMemory] = resource.MustParse(memoryLimit)
+	return project
+}
+
+func setResourceQuotaHard(hard corev1.ResourceList) corev1.ResourceQuota {
+	quota := setResourceQuota(0, 0)
+	quota.Spec.Hard = hard
+	return quota
+}
+
+func setProjectLimits(limits corev1.ResourceList) projectv1.Project {
+	project := setProject(0, 0)
+	project.Spec.ProjectLimits = limits
+	return project
+}
+
 func fillResourcequotaList(quotas... corev1.ResourceQuota) corev1.ResourceQuotaList {
 	list := corev1.ResourceQuotaList{
 		Items: quotas,
@@ -78,7 +108,7 @@ var _ = Describe("Testing allowOrDenyUpdateOrCreate function", func() {
 				},
 			}
 
-			reason := metav1.StatusReason("sum of resourceQuotas memory and cpu limits below project's limits, allow resourceQuota update")
+			reason := metav1.StatusReason("sum of resourceQuotas below project's limits, allow resourceQuota update")
 
 			//When
 			result := allowOrDenyUpdateOrCreate(project, quota, oldQuota, resourceQuotaList)
@@ -98,14 +128,15 @@ var _ = Describe("Testing allowOrDenyUpdateOrCreate function", func() {
 
 			resourceQuotaList := fillResourcequotaList(quota)
 
-			reason := metav1.StatusReason("resourceQuota cpu or memory increase is forbidden when project limits have been exceeded")
+			message := "resourceQuota increase is forbidden when project limits have been exceeded: limits.cpu"
 
 			//When
 			result := allowOrDenyUpdateOrCreate(project, quota, oldQuota, resourceQuotaList)
 
 			//Then
 			Expect(result.Allowed).To(BeFalse())
-			Expect(result.Result.Reason).To(Equal(reason))
+			Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+			Expect(result.Result.Message).To(Equal(message))
 		})
 
 		It("Should deny to increase resourceQuota's memory over the project's limits", func() {
@@ -118,14 +149,15 @@ var _ = Describe("Testing allowOrDenyUpdateOrCreate function", func() {
 
 			resourceQuotaList := fillResourcequotaList(quota)
 
-			reason := metav1.StatusReason("resourceQuota cpu or memory increase is forbidden when project limits have been exceeded")
+			message := "resourceQuota increase is forbidden when project limits have been exceeded: limits.memory"
 
 			//When
 			result := allowOrDenyUpdateOrCreate(project, quota, oldQuota, resourceQuotaList)
 
 			//Then
 			Expect(result.Allowed).To(BeFalse())
-			Expect(result.Result.Reason).To(Equal(reason))
+			Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+			Expect(result.Result.Message).To(Equal(message))
 		})
 	})
 
@@ -140,7 +172,7 @@ var _ = Describe("Testing allowOrDenyUpdateOrCreate function", func() {
 
 			otherResourceQuotas := fillResourcequotaList(currentQuota, quota1, quota2)
 
-			reason := metav1.StatusReason("sum of resourceQuotas memory and cpu limits below project's limits, allow resourceQuota update")
+			reason := metav1.StatusReason("sum of resourceQuotas below project's limits, allow resourceQuota update")
 
 			//When
 			result := allowOrDenyUpdateOrCreate(project, currentQuota, oldQuota, otherResourceQuotas)
@@ -160,14 +192,15 @@ var _ = Describe("Testing allowOrDenyUpdateOrCreate function", func() {
 
 			otherResourceQuotas := fillResourcequotaList(currentQuota, quota1, quota2)
 
-			reason := metav1.StatusReason("resourceQuota cpu or memory increase is forbidden when project limits have been exceeded")
+			message := "resourceQuota increase is forbidden when project limits have been exceeded: limits.cpu"
 
 			//When
 			result := allowOrDenyUpdateOrCreate(project, currentQuota, oldQuota, otherResourceQuotas)
 
 			//Then
 			Expect(result.Allowed).To(BeFalse())
-			Expect(result.Result.Reason).To(Equal(reason))
+			Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+			Expect(result.Result.Message).To(Equal(message))
 		})
 
 		It("Should deny the increase of resourceQuota memory if sum of resourceQuotas memory exceeds the project limit for memory", func() {
@@ -179,14 +212,15 @@ var _ = Describe("Testing allowOrDenyUpdateOrCreate function", func() {
 
 			otherResourceQuotas := fillResourcequotaList(currentQuota, quota1, quota2)
 
-			reason := metav1.StatusReason("resourceQuota cpu or memory increase is forbidden when project limits have been exceeded")
+			message := "resourceQuota increase is forbidden when project limits have been exceeded: limits.memory"
 
 			//When
 			result := allowOrDenyUpdateOrCreate(project, currentQuota, oldQuota, otherResourceQuotas)
 
 			//Then
 			Expect(result.Allowed).To(BeFalse())
-			Expect(result.Result.Reason).To(Equal(reason))
+			Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+			Expect(result.Result.Message).To(Equal(message))
 		})
 
 		It("Should allow the decrease of resourceQuota cpu even if sum of resourceQuotas cpu exceeds the project limit for cpu", func() {
@@ -198,7 +232,7 @@ var _ = Describe("Testing allowOrDenyUpdateOrCreate function", func() {
 
 			otherResourceQuotas := fillResourcequotaList(quotaMoreCpu, quota1, quota2)
 
-			reason := metav1.StatusReason("resourceQuota cpu and memory can be decreased no matter the limits")
+			reason := metav1.StatusReason("resourceQuota does not increase any resource limited by the project")
 
 			//When
 			result := allowOrDenyUpdateOrCreate(project, currentQuota, oldQuota, otherResourceQuotas)
@@ -216,7 +250,7 @@ var _ = Describe("Testing allowOrDenyUpdateOrCreate function", func() {
 			oldQuota := &quotaEvenMoreMemory
 			otherResourceQuotas := fillResourcequotaList(currentQuota, quota1, quota2)
 
-			reason := metav1.StatusReason("resourceQuota cpu and memory can be decreased no matter the limits")
+			reason := metav1.StatusReason("resourceQuota does not increase any resource limited by the project")
 
 			//When
 			result := allowOrDenyUpdateOrCreate(project, currentQuota, oldQuota, otherResourceQuotas)
@@ -226,6 +260,283 @@ var _ = Describe("Testing allowOrDenyUpdateOrCreate function", func() {
 			Expect(result.Result.Reason).To(Equal(reason))
 		})
 	})
+
+	Context("Sub-core and mixed-unit quotas", func() {
+		projectOneCore := setProjectFromStrings("1", "1Gi")
+		quotaHalfCore := setResourceQuotaFromStrings("500m", "0")
+		quotaMoreThanHalfCore := setResourceQuotaFromStrings("600m", "0")
+
+		It("Should allow two quotas of 500m cpu in a project limited to 1 cpu", func() {
+			//Given
+			project := projectOneCore
+			oldQuota := &quotaDefault
+			otherResourceQuotas := fillResourcequotaList(quotaHalfCore, quotaHalfCore)
+
+			reason := metav1.StatusReason("sum of resourceQuotas below project's limits, allow resourceQuota update")
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quotaHalfCore, oldQuota, otherResourceQuotas)
+
+			//Then
+			Expect(result.Allowed).To(BeTrue())
+			Expect(result.Result.Reason).To(Equal(reason))
+		})
+
+		It("Should deny an increase from 500m to 600m cpu when the sum exceeds 1 cpu", func() {
+			//Given
+			project := projectOneCore
+			oldQuota := &quotaHalfCore
+			otherResourceQuotas := fillResourcequotaList(quotaMoreThanHalfCore, quotaHalfCore)
+
+			message := "resourceQuota increase is forbidden when project limits have been exceeded: limits.cpu"
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quotaMoreThanHalfCore, oldQuota, otherResourceQuotas)
+
+			//Then
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+			Expect(result.Result.Message).To(Equal(message))
+		})
+
+		It("Should compare memory expressed in different units exactly", func() {
+			//Given
+			project := projectOneCore
+			quota := setResourceQuotaFromStrings("0", "512Mi")
+			oldQuota := &quotaDefault
+			otherResourceQuotas := fillResourcequotaList(quota, setResourceQuotaFromStrings("0", "536870912"))
+
+			reason := metav1.StatusReason("sum of resourceQuotas below project's limits, allow resourceQuota update")
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quota, oldQuota, otherResourceQuotas)
+
+			//Then
+			Expect(result.Allowed).To(BeTrue())
+			Expect(result.Result.Reason).To(Equal(reason))
+		})
+	})
+
+	Context("Resources other than cpu and memory", func() {
+		project := setProjectLimits(corev1.ResourceList{
+			corev1.ResourcePods:                   resource.MustParse("10"),
+			corev1.ResourceRequestsStorage:        resource.MustParse("100Gi"),
+			corev1.ResourcePersistentVolumeClaims: resource.MustParse("5"),
+			"count/deployments.apps":              resource.MustParse("4"),
+			"nvidia.com/gpu":                      resource.MustParse("2"),
+		})
+		quotaEmpty := setResourceQuotaHard(corev1.ResourceList{})
+
+		It("Should deny to increase the number of pods over the project's limits", func() {
+			//Given
+			quota := setResourceQuotaHard(corev1.ResourceList{corev1.ResourcePods: resource.MustParse("6")})
+			otherResourceQuotas := fillResourcequotaList(quota, quota)
+
+			message := "resourceQuota increase is forbidden when project limits have been exceeded: pods"
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quota, &quotaEmpty, otherResourceQuotas)
+
+			//Then
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+			Expect(result.Result.Message).To(Equal(message))
+		})
+
+		It("Should count extended resources requested in resourceQuotas against the project's limits", func() {
+			//Given
+			quota := setResourceQuotaHard(corev1.ResourceList{"requests.nvidia.com/gpu": resource.MustParse("2")})
+			otherResourceQuotas := fillResourcequotaList(quota, setResourceQuotaHard(corev1.ResourceList{"requests.nvidia.com/gpu": resource.MustParse("1")}))
+
+			message := "resourceQuota increase is forbidden when project limits have been exceeded: nvidia.com/gpu"
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quota, &quotaEmpty, otherResourceQuotas)
+
+			//Then
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+			Expect(result.Result.Message).To(Equal(message))
+		})
+
+		It("Should allow to increase storage, claims and object counts below the project's limits", func() {
+			//Given
+			quota := setResourceQuotaHard(corev1.ResourceList{
+				corev1.ResourceRequestsStorage:        resource.MustParse("50Gi"),
+				corev1.ResourcePersistentVolumeClaims: resource.MustParse("5"),
+				"count/deployments.apps":              resource.MustParse("4"),
+			})
+			otherResourceQuotas := fillResourcequotaList(quota, setResourceQuotaHard(corev1.ResourceList{corev1.ResourceRequestsStorage: resource.MustParse("50Gi")}))
+
+			reason := metav1.StatusReason("sum of resourceQuotas below project's limits, allow resourceQuota update")
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quota, &quotaEmpty, otherResourceQuotas)
+
+			//Then
+			Expect(result.Allowed).To(BeTrue())
+			Expect(result.Result.Reason).To(Equal(reason))
+		})
+
+		It("Should count a requests.cpu without limits.cpu against the project's limits.cpu", func() {
+			//Given
+			project := setProject(10, 10000)
+			quota := setResourceQuotaHard(corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("11")})
+
+			message := "resourceQuota increase is forbidden when project limits have been exceeded: limits.cpu"
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quota, nil, fillResourcequotaList(quota))
+
+			//Then
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+			Expect(result.Result.Message).To(Equal(message))
+		})
+
+		It("Should not limit resources that are not declared in the project's limits", func() {
+			//Given
+			quota := setResourceQuotaHard(corev1.ResourceList{corev1.ResourceServices: resource.MustParse("1000")})
+			otherResourceQuotas := fillResourcequotaList(quota)
+
+			reason := metav1.StatusReason("resourceQuota does not increase any resource limited by the project")
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quota, &quotaEmpty, otherResourceQuotas)
+
+			//Then
+			Expect(result.Allowed).To(BeTrue())
+			Expect(result.Result.Reason).To(Equal(reason))
+		})
+	})
+
+	Context("Creation of a resourceQuota", func() {
+		It("Should deny the creation of a resourceQuota that pushes the project over its limits", func() {
+			//Given
+			project := project1
+			quota := quotaExceedMemory
+			otherResourceQuotas := fillResourcequotaList(quota1, quota)
+
+			message := "resourceQuota increase is forbidden when project limits have been exceeded: limits.memory"
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quota, nil, otherResourceQuotas)
+
+			//Then
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+			Expect(result.Result.Message).To(Equal(message))
+		})
+
+		It("Should allow the creation of an empty resourceQuota even if the project's limits have been exceeded", func() {
+			//Given
+			project := project1
+			quota := quotaDefault
+			otherResourceQuotas := fillResourcequotaList(quota2, quotaExceedCpu, quota)
+
+			reason := metav1.StatusReason("allow creation of resourceQuota, it does not increase resource usage in the project")
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quota, nil, otherResourceQuotas)
+
+			//Then
+			Expect(result.Allowed).To(BeTrue())
+			Expect(result.Result.Reason).To(Equal(reason))
+		})
+	})
+
+	Context("Project with overcommit", func() {
+		project := setProjectLimits(corev1.ResourceList{
+			corev1.ResourceLimitsCPU:   resource.MustParse("10"),
+			corev1.ResourceRequestsCPU: resource.MustParse("10"),
+		})
+		project.Spec.Overcommit = corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("1.5")}
+		otherQuota := setResourceQuotaHard(corev1.ResourceList{
+			corev1.ResourceLimitsCPU:   resource.MustParse("10"),
+			corev1.ResourceRequestsCPU: resource.MustParse("5"),
+		})
+
+		It("Should allow limits.* above the project's limits up to the overcommit factor", func() {
+			//Given
+			quota := setResourceQuotaHard(corev1.ResourceList{
+				corev1.ResourceLimitsCPU:   resource.MustParse("5"),
+				corev1.ResourceRequestsCPU: resource.MustParse("5"),
+			})
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quota, nil, fillResourcequotaList(otherQuota, quota))
+
+			//Then
+			Expect(result.Allowed).To(BeTrue())
+		})
+
+		It("Should deny limits.* beyond the overcommit factor", func() {
+			//Given
+			quota := setResourceQuotaHard(corev1.ResourceList{corev1.ResourceLimitsCPU: resource.MustParse("5001m")})
+
+			message := "resourceQuota increase is forbidden when project limits have been exceeded: limits.cpu"
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quota, nil, fillResourcequotaList(otherQuota, quota))
+
+			//Then
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+			Expect(result.Result.Message).To(Equal(message))
+		})
+
+		It("Should keep requests.* strictly capped", func() {
+			//Given
+			quota := setResourceQuotaHard(corev1.ResourceList{
+				corev1.ResourceLimitsCPU:   resource.MustParse("5"),
+				corev1.ResourceRequestsCPU: resource.MustParse("6"),
+			})
+
+			message := "resourceQuota increase is forbidden when project limits have been exceeded: requests.cpu"
+
+			//When
+			result := allowOrDenyUpdateOrCreate(project, quota, nil, fillResourcequotaList(otherQuota, quota))
+
+			//Then
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.Result.Reason).To(Equal(ReasonLimitsExceeded))
+			Expect(result.Result.Message).To(Equal(message))
+		})
+	})
+})
+
+
+var _ = Describe("Testing function withResourceQuota", func() {
+
+	It("Should replace the stored version of an updated resourceQuota", func() {
+		//Given
+		stored := setResourceQuota(10, 1000)
+		stored.Namespace = "test1"
+		other := setResourceQuota(80, 8000)
+		other.Namespace = "test2"
+		updated := setResourceQuota(20, 1000)
+		updated.Namespace = "test1"
+
+		//When
+		result := withResourceQuota(fillResourcequotaList(stored, other), updated)
+
+		//Then
+		Expect(result.Items).To(ConsistOf(other, updated))
+	})
+
+	It("Should add a created resourceQuota", func() {
+		//Given
+		stored := setResourceQuota(10, 1000)
+		stored.Namespace = "test1"
+		created := setResourceQuota(20, 1000)
+		created.Namespace = "test2"
+
+		//When
+		result := withResourceQuota(fillResourcequotaList(stored), created)
+
+		//Then
+		Expect(result.Items).To(ConsistOf(stored, created))
+	})
 })
 
 
@@ -235,14 +546,15 @@ var _ = Describe("Testing function allowOrDenyDelete", func() {
 		//Given
 		namespace := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test1", DeletionTimestamp: nil}}
 
-		reason := metav1.StatusReason("Namespace not terminating")
+		message := "Namespace not terminating"
 
 		//when
 		result := allowOrDenyDelete(namespace)
 
 		//Then
 		Expect(result.Allowed).To(BeFalse())
-		Expect(result.Result.Reason).To(Equal(reason))
+		Expect(result.Result.Reason).To(Equal(ReasonQuotaProtected))
+		Expect(result.Result.Message).To(Equal(message))
 
 	})
 
@@ -262,6 +574,39 @@ var _ = Describe("Testing function allowOrDenyDelete", func() {
 	})
 })
 
+var _ = Describe("Testing function recordDenied", func() {
+	project := setProject(100, 10000)
+	req := admission.Request{AdmissionRequest: v1beta1.AdmissionRequest{Name: "project-quota", Namespace: "test1"}}
+
+	It("Should emit a warning event on the project of a denied resourceQuota", func() {
+		//Given
+		recorder := record.NewFakeRecorder(1)
+		validator := ResourceQuotaValidator{Recorder: recorder}
+
+		//When
+		validator.recordDenied(req, &project, denied(ReasonLimitsExceeded, "resourceQuota increase is forbidden when project limits have been exceeded: cpu"))
+
+		//Then
+		Expect(recorder.Events).To(Receive(Equal("Warning QuotaDenied resourceQuota test1/project-quota denied: resourceQuota increase is forbidden when project limits have been exceeded: cpu")))
+	})
+
+	It("Should not emit an event for an allowed resourceQuota or a dry run", func() {
+		//Given
+		recorder := record.NewFakeRecorder(1)
+		validator := ResourceQuotaValidator{Recorder: recorder}
+		dryRun := true
+		dryRunReq := req
+		dryRunReq.DryRun = &dryRun
+
+		//When
+		validator.recordDenied(req, &project, admission.Allowed("resourceQuota does not increase any resource limited by the project"))
+		validator.recordDenied(dryRunReq, &project, admission.Denied("denied"))
+
+		//Then
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
+
 func  Test_allowOrDenyUpdateOrCreate(t *testing.T) {
 
 	quotaDefault := setResourceQuota(0,0)
@@ -272,6 +617,8 @@ func  Test_allowOrDenyUpdateOrCreate(t *testing.T) {
 	quotaEvenMoreCpu := setResourceQuota(12, 0)
 	quotaEvenMoreMemory := setResourceQuota(0, 1002)
 
+	quotaHalfCore := setResourceQuotaFromStrings("500m", "0")
+
 	project1 := setProject(100, 10000)
 
 	type args struct {
@@ -300,7 +647,7 @@ func  Test_allowOrDenyUpdateOrCreate(t *testing.T) {
 			},
 			want:want {
 			Allowed: true,
-			Reason: metav1.StatusReason("allow creation of resourceQuota, by default it does not increase cpu or memory usage in the project"),
+			Reason: metav1.StatusReason("allow creation of resourceQuota, it does not increase resource usage in the project"),
 			},
 		},
 
@@ -314,7 +661,7 @@ func  Test_allowOrDenyUpdateOrCreate(t *testing.T) {
 			},
 			want:want {
 				Allowed: true,
-				Reason: metav1.StatusReason("allow creation of resourceQuota, by default it does not increase cpu or memory usage in the project"),
+				Reason: metav1.StatusReason("allow creation of resourceQuota, it does not increase resource usage in the project"),
 			},
 		},
 
@@ -328,7 +675,7 @@ func  Test_allowOrDenyUpdateOrCreate(t *testing.T) {
 			},
 			want:want {
 				Allowed: true,
-				Reason: metav1.StatusReason("sum of resourceQuotas memory and cpu limits below project's limits, allow resourceQuota update"),
+				Reason: metav1.StatusReason("sum of resourceQuotas below project's limits, allow resourceQuota update"),
 			},
 		},
 
@@ -342,7 +689,7 @@ func  Test_allowOrDenyUpdateOrCreate(t *testing.T) {
 			},
 			want:want {
 				Allowed: false,
-				Reason: metav1.StatusReason("resourceQuota cpu or memory increase is forbidden when project limits have been exceeded"),
+				Reason: ReasonLimitsExceeded,
 			},
 		},
 
@@ -356,7 +703,7 @@ func  Test_allowOrDenyUpdateOrCreate(t *testing.T) {
 			},
 			want:want {
 				Allowed: false,
-				Reason: metav1.StatusReason("resourceQuota cpu or memory increase is forbidden when project limits have been exceeded"),
+				Reason: ReasonLimitsExceeded,
 			},
 		},
 
@@ -370,7 +717,7 @@ func  Test_allowOrDenyUpdateOrCreate(t *testing.T) {
 			},
 			want:want {
 				Allowed: true,
-				Reason: metav1.StatusReason("resourceQuota cpu and memory can be decreased no matter the limits"),
+				Reason: metav1.StatusReason("resourceQuota does not increase any resource limited by the project"),
 			},
 		},
 
@@ -384,7 +731,102 @@ func  Test_allowOrDenyUpdateOrCreate(t *testing.T) {
 			},
 			want:want {
 				Allowed: true,
-				Reason: metav1.StatusReason("resourceQuota cpu and memory can be decreased no matter the limits"),
+				Reason: metav1.StatusReason("resourceQuota does not increase any resource limited by the project"),
+			},
+		},
+		{
+			name: "testing resourceQuota increase of millicores below project's Cpu limit",
+			args:args{
+				project: setProjectFromStrings("1", "1Gi"),
+				quota: setResourceQuotaFromStrings("500m", "0"),
+				oldQuota: &quotaDefault,
+				allResourceQuotas: fillResourcequotaList(setResourceQuotaFromStrings("500m", "0"), setResourceQuotaFromStrings("500m", "0")),
+			},
+			want:want {
+				Allowed: true,
+				Reason: metav1.StatusReason("sum of resourceQuotas below project's limits, allow resourceQuota update"),
+			},
+		},
+
+		{
+			name: "testing resourceQuota increase of millicores above project's Cpu limit",
+			args:args{
+				project: setProjectFromStrings("1500m", "1Gi"),
+				quota: setResourceQuotaFromStrings("900m", "0"),
+				oldQuota: &quotaDefault,
+				allResourceQuotas: fillResourcequotaList(setResourceQuotaFromStrings("700m", "0"), setResourceQuotaFromStrings("900m", "0")),
+			},
+			want:want {
+				Allowed: false,
+				Reason: ReasonLimitsExceeded,
+			},
+		},
+
+		{
+			name: "testing resourceQuota increase within the same core above project's Cpu limit",
+			args:args{
+				project: setProjectFromStrings("1", "1Gi"),
+				quota: setResourceQuotaFromStrings("600m", "0"),
+				oldQuota: &quotaHalfCore,
+				allResourceQuotas: fillResourcequotaList(setResourceQuotaFromStrings("600m", "0"), setResourceQuotaFromStrings("500m", "0")),
+			},
+			want:want {
+				Allowed: false,
+				Reason: ReasonLimitsExceeded,
+			},
+		},
+
+		{
+			name: "testing resourceQuota increase of memory in mixed units above project's memory limit",
+			args:args{
+				project: setProjectFromStrings("1", "1G"),
+				quota: setResourceQuotaFromStrings("0", "1Gi"),
+				oldQuota: &quotaDefault,
+				allResourceQuotas: fillResourcequotaList(setResourceQuotaFromStrings("0", "1Gi")),
+			},
+			want:want {
+				Allowed: false,
+				Reason: ReasonLimitsExceeded,
+			},
+		},
+		{
+			name: "testing resourceQuota increase of services above project's services limit",
+			args:args{
+				project: setProjectLimits(corev1.ResourceList{corev1.ResourceServices: resource.MustParse("3")}),
+				quota: setResourceQuotaHard(corev1.ResourceList{corev1.ResourceServices: resource.MustParse("2")}),
+				oldQuota: &quotaDefault,
+				allResourceQuotas: fillResourcequotaList(setResourceQuotaHard(corev1.ResourceList{corev1.ResourceServices: resource.MustParse("2")}), setResourceQuotaHard(corev1.ResourceList{corev1.ResourceServices: resource.MustParse("2")})),
+			},
+			want:want {
+				Allowed: false,
+				Reason: ReasonLimitsExceeded,
+			},
+		},
+		{
+			name: "testing resourceQuota creation with cpu and memory below project's limits",
+			args:args{
+				project: project1,
+				quota: quota1,
+				oldQuota: nil,
+				allResourceQuotas: fillResourcequotaList(quota1, quota2),
+			},
+			want:want {
+				Allowed: true,
+				Reason: metav1.StatusReason("sum of resourceQuotas below project's limits, allow resourceQuota update"),
+			},
+		},
+
+		{
+			name: "testing resourceQuota creation with cpu above project's Cpu limit",
+			args:args{
+				project: project1,
+				quota: quotaMoreCpu,
+				oldQuota: nil,
+				allResourceQuotas: fillResourcequotaList(quota1, quota2, quotaMoreCpu),
+			},
+			want:want {
+				Allowed: false,
+				Reason: ReasonLimitsExceeded,
 			},
 		},
 	}
@@ -405,4 +847,92 @@ func  Test_allowOrDenyUpdateOrCreate(t *testing.T) {
 
 		})
 	}
-}
\ No newline at end of file
+}
+var _ = Describe("Testing function allowOrDenyUncounted", func() {
+
+	It("Should allow resourceQuotas that are not counted against the project", func() {
+		//When
+		result := allowOrDenyUncounted(budget.ManagedQuota)
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+
+	It("Should deny resourceQuotas other than the project-quota in exclusive mode", func() {
+		//Given
+		message := "only the project-quota resourceQuota is allowed in the namespaces of a project"
+
+		//When
+		result := allowOrDenyUncounted(budget.Exclusive)
+
+		//Then
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Reason).To(Equal(ReasonForeignQuota))
+		Expect(result.Result.Message).To(Equal(message))
+	})
+})
+
+var _ = Describe("Testing function perNamespace", func() {
+
+	It("Should count the tightest resourceQuota of a namespace against the project's limits", func() {
+		//Given
+		project := setProject(100, 10000)
+		loose := setResourceQuota(60, 1000)
+		loose.Namespace = "test1"
+		tight := setResourceQuota(10, 1000)
+		tight.Name = "scoped"
+		tight.Namespace = "test1"
+		other := setResourceQuota(80, 8000)
+		other.Namespace = "test2"
+
+		//When
+		result := allowOrDenyUpdateOrCreate(project, loose, &[]corev1.ResourceQuota{setResourceQuota(0, 0)}[0], perNamespace(fillResourcequotaList(loose, tight, other)))
+
+		//Then
+		Expect(result.Allowed).To(BeTrue())
+	})
+})
+
+var _ = Describe("Testing function effectiveChange", func() {
+
+	project := setProject(100, 10000)
+	full := setResourceQuota(95, 0)
+	full.Namespace = "test0"
+	loose := setResourceQuota(10, 0)
+	loose.Namespace = "test1"
+
+	It("Should allow a new quota tighter than the namespace's quota in a project over its limits", func() {
+		//Given
+		quota := setResourceQuota(8, 0)
+		quota.Name = "tight"
+		quota.Namespace = "test1"
+		resourceQuotaList := fillResourcequotaList(full, loose)
+
+		//When
+		counted, oldQuota := effectiveChange(resourceQuotaList, quota)
+		result := allowOrDenyUpdateOrCreate(project, counted, oldQuota, perNamespace(withResourceQuota(resourceQuotaList, counted)))
+
+		//Then
+		Expect(oldQuota).NotTo(BeNil())
+		Expect(result.Allowed).To(BeTrue())
+	})
+
+	It("Should count a new quota as an increase from nothing in a namespace without quota", func() {
+		//Given
+		quota := setResourceQuota(8, 0)
+		quota.Name = "tight"
+		quota.Namespace = "test2"
+		resourceQuotaList := fillResourcequotaList(full, loose)
+
+		message := "resourceQuota increase is forbidden when project limits have been exceeded: limits.cpu"
+
+		//When
+		counted, oldQuota := effectiveChange(resourceQuotaList, quota)
+		result := allowOrDenyUpdateOrCreate(project, counted, oldQuota, perNamespace(withResourceQuota(resourceQuotaList, counted)))
+
+		//Then
+		Expect(oldQuota).To(BeNil())
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Result.Message).To(Equal(message))
+	})
+})
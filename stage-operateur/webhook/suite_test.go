@@ -19,6 +19,7 @@ package webhook
 import (
 	"path/filepath"
 	"project/controllers"
+	"project/index"
 	"testing"
 	"time"
 
@@ -80,18 +81,23 @@ var _ = BeforeSuite(func(done Done) {
 	})
 	Expect(err).NotTo(HaveOccurred(), "failed to create manager")
 
+	err = index.Setup(mgr.GetFieldIndexer(), projectv1.Names{})
+	Expect(err).NotTo(HaveOccurred(), "failed to setup field indexes")
+
 	rp := &controllers.ProjectReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Project"),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("Project"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("project-controller"),
 	}
 	err = rp.SetupWithManager(mgr)
 	Expect(err).NotTo(HaveOccurred(), "failed to setup Project controller")
 
 	rn := &controllers.NamespaceReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Namespace"),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("Namespace"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("namespace-controller"),
 	}
 	err = rn.SetupWithManager(mgr)
 	Expect(err).NotTo(HaveOccurred(), "failed to setup Namespace controller")